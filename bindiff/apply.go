@@ -0,0 +1,228 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package bindiff
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Git deltas longer than this are allocated as they are decoded
+const maxPreallocation = 1 << 24
+
+// Apply decodes a VCDIFF delta against source and returns the target. It
+// accepts deltas that use the default code table and no secondary
+// compression, which includes those made by Delta, and windows that copy
+// from the source or from earlier windows of the target. Windows of more
+// than 16 MiB are rejected, since a few bytes of RUN or COPY instructions
+// could otherwise make it allocate any amount of memory.
+func Apply(source, delta []byte) ([]byte, error) {
+	r := reader{delta}
+	header, err := r.bytes(len(magic))
+	if err != nil || !bytes.Equal(header, magic) {
+		return nil, fmt.Errorf("not a VCDIFF delta")
+	}
+	indicator, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if indicator&vcdDecompress != 0 {
+		return nil, fmt.Errorf("secondary compression is not supported")
+	}
+	if indicator&vcdCodeTable != 0 {
+		return nil, fmt.Errorf("custom code tables are not supported")
+	}
+	if indicator&vcdAppHeader != 0 {
+		length, err := r.int()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := r.bytes(length); err != nil {
+			return nil, err
+		}
+	}
+
+	target := []byte{}
+	for len(r.data) > 0 {
+		offset := len(target)
+		target, err = applyWindow(&r, source, target)
+		if err != nil {
+			return nil, fmt.Errorf("window at target offset %v: %w", offset, err)
+		}
+	}
+	return target, nil
+}
+
+// applyWindow decodes the next window of a delta and appends it to target.
+func applyWindow(r *reader, source, target []byte) ([]byte, error) {
+	indicator, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if indicator&^(vcdSource|vcdTarget) != 0 {
+		return nil, fmt.Errorf("unsupported window indicator %#x", indicator)
+	}
+
+	var segment []byte
+	if indicator&(vcdSource|vcdTarget) != 0 {
+		if indicator == vcdSource|vcdTarget {
+			return nil, fmt.Errorf("window copies from both source and target")
+		}
+		size, err := r.int()
+		if err != nil {
+			return nil, err
+		}
+		position, err := r.int()
+		if err != nil {
+			return nil, err
+		}
+		from := source
+		if indicator&vcdTarget != 0 {
+			from = target
+		}
+		if position > len(from) || size > len(from)-position {
+			return nil, fmt.Errorf("segment out of range")
+		}
+		segment = from[position : position+size]
+	}
+
+	length, err := r.int()
+	if err != nil {
+		return nil, err
+	}
+	encoding, err := r.bytes(length)
+	if err != nil {
+		return nil, err
+	}
+	w := reader{encoding}
+
+	var lengths [4]int // Target window, data, instructions and addresses
+	lengths[0], err = w.int()
+	if err != nil {
+		return nil, err
+	}
+	deltaIndicator, err := w.byte()
+	if err != nil {
+		return nil, err
+	}
+	if deltaIndicator != 0 {
+		return nil, fmt.Errorf("secondary compression is not supported")
+	}
+	for i := 1; i < len(lengths); i++ {
+		if lengths[i], err = w.int(); err != nil {
+			return nil, err
+		}
+	}
+	var sections [3]reader // Data, instructions and addresses
+	for i := range sections {
+		if sections[i].data, err = w.bytes(lengths[i+1]); err != nil {
+			return nil, err
+		}
+	}
+	if len(w.data) != 0 {
+		return nil, fmt.Errorf("unexpected data after the window")
+	}
+	data, instructions, addresses := &sections[0], &sections[1], &sections[2]
+
+	if lengths[0] > maxWindowSize {
+		return nil, fmt.Errorf("window of %v bytes is larger than the maximum of %v", lengths[0], maxWindowSize)
+	}
+	window := make([]byte, 0, lengths[0])
+	var cache addressCache
+	for len(instructions.data) > 0 {
+		opcode, _ := instructions.byte()
+		for _, inst := range defaultCodeTable[opcode] {
+			if inst.kind == instNoop {
+				continue
+			}
+			size := int(inst.size)
+			if size == 0 {
+				if size, err = instructions.int(); err != nil {
+					return nil, err
+				}
+			}
+			if size > lengths[0]-len(window) {
+				return nil, fmt.Errorf("instructions overflow the target window")
+			}
+
+			switch inst.kind {
+			case instAdd:
+				added, err := data.bytes(size)
+				if err != nil {
+					return nil, err
+				}
+				window = append(window, added...)
+			case instRun:
+				b, err := data.byte()
+				if err != nil {
+					return nil, err
+				}
+				for i := 0; i < size; i++ {
+					window = append(window, b)
+				}
+			case instCopy:
+				here := len(segment) + len(window)
+				addr, err := decodeAddress(addresses, &cache, inst.mode, here)
+				if err != nil {
+					return nil, err
+				}
+				// The copy can overlap the bytes it produces, so go byte by
+				// byte
+				for i := 0; i < size; i++ {
+					if addr+i < len(segment) {
+						window = append(window, segment[addr+i])
+					} else {
+						window = append(window, window[addr+i-len(segment)])
+					}
+				}
+			}
+		}
+	}
+
+	if len(window) != lengths[0] {
+		return nil, fmt.Errorf("expected a window of %v bytes, got %v", lengths[0], len(window))
+	}
+	if len(data.data) != 0 || len(addresses.data) != 0 {
+		return nil, fmt.Errorf("unused data or addresses")
+	}
+	return append(target, window...), nil
+}
+
+// decodeAddress reads the address of a COPY in the given mode, section 5.3
+// of RFC 3284.
+func decodeAddress(addresses *reader, cache *addressCache, mode byte, here int) (int, error) {
+	var addr int
+	switch {
+	case mode == modeSelf:
+		n, err := addresses.int()
+		if err != nil {
+			return 0, err
+		}
+		addr = n
+	case mode == modeHere:
+		n, err := addresses.int()
+		if err != nil {
+			return 0, err
+		}
+		addr = here - n
+	case mode < modeSame:
+		n, err := addresses.int()
+		if err != nil {
+			return 0, err
+		}
+		addr = cache.near[mode-modeNear] + n
+	default:
+		b, err := addresses.byte()
+		if err != nil {
+			return 0, err
+		}
+		addr = cache.same[int(mode-modeSame)*256+int(b)]
+	}
+	if addr < 0 || addr >= here {
+		return 0, fmt.Errorf("copy address %v out of range", addr)
+	}
+	cache.update(addr)
+	return addr, nil
+}
@@ -0,0 +1,255 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package bindiff makes and applies binary deltas in the VCDIFF format of
+// RFC 3284 and in git's pack delta format, so that changes to binary files
+// can be exchanged like diffs of text files.
+package bindiff
+
+// Matches are found by looking up blocks of this many bytes, so shorter
+// matches are not used
+const blockSize = 16
+
+// The base of the rolling hash, the 32 bit FNV prime
+const hashBase = 0x01000193
+
+// hashBase to the power of blockSize-1, which is how much the first byte of
+// a block counts in its hash
+var hashHighPower = func() uint32 {
+	power := uint32(1)
+	for i := 1; i < blockSize; i++ {
+		power *= hashBase
+	}
+	return power
+}()
+
+// hash returns the rolling hash of a block.
+func hash(block []byte) uint32 {
+	var h uint32
+	for _, b := range block {
+		h = h*hashBase + uint32(b)
+	}
+	return h
+}
+
+// roll updates the hash of a block for the block one byte later, which
+// drops out and adds in.
+func roll(h uint32, out, in byte) uint32 {
+	return (h-uint32(out)*hashHighPower)*hashBase + uint32(in)
+}
+
+// Delta returns a VCDIFF delta that turns source into target, for Apply.
+// Each window of the delta describes up to 16 MiB of the target, copying
+// from the whole source and from the window itself.
+func Delta(source, target []byte) []byte {
+	delta := append([]byte{}, magic...)
+	delta = append(delta, 0) // No compression or custom code table
+	for start := 0; start < len(target); start += maxWindowSize {
+		end := start + maxWindowSize
+		if end > len(target) {
+			end = len(target)
+		}
+		e := encoder{source: source, target: target[start:end]}
+		findMatches(source, e.target, true, &e)
+		delta = e.appendWindow(delta)
+	}
+	return delta
+}
+
+// deltaWriter receives the instructions of a delta as they are found.
+type deltaWriter interface {
+	add(b []byte)
+	copy(addr, size int)
+}
+
+// findMatches describes target to w as ADDs of new bytes and COPYs of bytes
+// of source, or of the target itself if selfCopies is true. Addresses of the
+// target come after those of the source.
+//
+// Every block of blockSize bytes of source at a multiple of blockSize is
+// indexed by its rolling hash, and so is each such block of target once it
+// has been described. Target is scanned with the rolling hash, and each
+// block found in the index is extended as far as it matches, both forwards
+// and backwards, and becomes a COPY. Like the algorithm of Bentley and
+// McIlroy, this finds most matches of at least 2·blockSize-1 bytes. Copies
+// from the target can overlap the bytes they produce, so long runs of a
+// byte take only a few instructions.
+func findMatches(source, target []byte, selfCopies bool, w deltaWriter) {
+	m := matcher{source: source, target: target, index: map[uint32]int{}}
+	for p := 0; p+blockSize <= len(source); p += blockSize {
+		m.insert(source[p:p+blockSize], p)
+	}
+
+	addStart := 0
+	nextBlock := 0 // The next block of target to index
+	var h uint32
+	hashed := false
+	for i := 0; i+blockSize <= len(target); {
+		for ; selfCopies && nextBlock < i && nextBlock+blockSize <= len(target); nextBlock += blockSize {
+			m.insert(target[nextBlock:nextBlock+blockSize], len(source)+nextBlock)
+		}
+		if !hashed {
+			h = hash(target[i : i+blockSize])
+			hashed = true
+		}
+
+		if addr, ok := m.index[h]; ok {
+			if length := m.matchLength(addr, i); length >= blockSize {
+				back := m.matchBackwards(addr, i, addStart)
+				if addStart < i-back {
+					w.add(target[addStart : i-back])
+				}
+				w.copy(addr-back, length+back)
+				i += length
+				addStart = i
+				hashed = false
+				continue
+			}
+		}
+
+		if i+blockSize < len(target) {
+			h = roll(h, target[i], target[i+blockSize])
+		}
+		i++
+	}
+	if addStart < len(target) {
+		w.add(target[addStart:])
+	}
+}
+
+// matcher finds the matches of findMatches.
+type matcher struct {
+	source, target []byte
+	index          map[uint32]int // Address of a block with each hash
+}
+
+// insert indexes a block at an address, unless a block with the same hash is
+// already there.
+func (m *matcher) insert(block []byte, addr int) {
+	h := hash(block)
+	if _, ok := m.index[h]; !ok {
+		m.index[h] = addr
+	}
+}
+
+// at returns the byte at an address. Addresses below the length of the
+// source are in the source, and the rest are in the target.
+func (m *matcher) at(addr int) byte {
+	if addr < len(m.source) {
+		return m.source[addr]
+	}
+	return m.target[addr-len(m.source)]
+}
+
+// matchLength returns how many bytes from addr match the target from i. A
+// match in the source stops at the end of the source.
+func (m *matcher) matchLength(addr, i int) int {
+	end := len(m.target) - i
+	if addr < len(m.source) && len(m.source)-addr < end {
+		end = len(m.source) - addr
+	}
+	length := 0
+	for length < end && m.at(addr+length) == m.target[i+length] {
+		length++
+	}
+	return length
+}
+
+// matchBackwards returns how many bytes before addr match the target before
+// i, without going back past start.
+func (m *matcher) matchBackwards(addr, i, start int) int {
+	first := 0
+	if addr >= len(m.source) {
+		first = len(m.source)
+	}
+	back := 0
+	for i-back > start && addr-back > first && m.at(addr-back-1) == m.target[i-back-1] {
+		back++
+	}
+	return back
+}
+
+// encoder builds the sections of a window of a VCDIFF delta.
+type encoder struct {
+	source, target []byte
+
+	data, instructions, addresses []byte
+	cache                         addressCache
+	position                      int // How much of the target is encoded
+}
+
+// add encodes an ADD of the given bytes.
+func (e *encoder) add(b []byte) {
+	// Opcodes 2 to 18 are ADDs of 1 to 17 bytes, and 1 is an ADD with an
+	// explicit size
+	if len(b) <= 17 {
+		e.instructions = append(e.instructions, byte(1+len(b)))
+	} else {
+		e.instructions = append(e.instructions, 1)
+		e.instructions = appendInt(e.instructions, len(b))
+	}
+	e.data = append(e.data, b...)
+	e.position += len(b)
+}
+
+// copy encodes a COPY of size bytes from addr, choosing the address mode
+// that takes the fewest bytes.
+func (e *encoder) copy(addr, size int) {
+	here := len(e.source) + e.position
+	var mode byte
+	var encoded []byte
+	if same := addr % len(e.cache.same); e.cache.same[same] == addr {
+		mode = byte(modeSame + same/256)
+		encoded = []byte{byte(same % 256)}
+	} else {
+		mode = modeSelf
+		encoded = appendInt(nil, addr)
+		candidates := []int{here - addr}
+		for _, near := range e.cache.near {
+			candidates = append(candidates, addr-near)
+		}
+		for k, offset := range candidates {
+			if offset >= 0 && len(appendInt(nil, offset)) < len(encoded) {
+				mode = byte(modeHere + k)
+				encoded = appendInt(nil, offset)
+			}
+		}
+	}
+	e.cache.update(addr)
+
+	// Each mode has a COPY with an explicit size, followed by COPYs of 4 to
+	// 18 bytes
+	opcode := 19 + 16*int(mode)
+	if size >= 4 && size <= 18 {
+		e.instructions = append(e.instructions, byte(opcode+size-3))
+	} else {
+		e.instructions = append(e.instructions, byte(opcode))
+		e.instructions = appendInt(e.instructions, size)
+	}
+	e.addresses = append(e.addresses, encoded...)
+	e.position += size
+}
+
+// appendWindow appends the encoded window to a delta.
+func (e *encoder) appendWindow(delta []byte) []byte {
+	if len(e.source) > 0 {
+		delta = append(delta, vcdSource)
+		delta = appendInt(delta, len(e.source))
+		delta = appendInt(delta, 0)
+	} else {
+		delta = append(delta, 0)
+	}
+
+	encoding := appendInt(nil, len(e.target))
+	encoding = append(encoding, 0) // No compression of the sections
+	encoding = appendInt(encoding, len(e.data))
+	encoding = appendInt(encoding, len(e.instructions))
+	encoding = appendInt(encoding, len(e.addresses))
+	encoding = append(encoding, e.data...)
+	encoding = append(encoding, e.instructions...)
+	encoding = append(encoding, e.addresses...)
+
+	delta = appendInt(delta, len(encoding))
+	return append(delta, encoding...)
+}
@@ -0,0 +1,195 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package bindiff
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// mutate returns a copy of b with some bytes changed, inserted, deleted and
+// moved.
+func mutate(r *rand.Rand, b []byte) []byte {
+	result := append([]byte{}, b...)
+	for edits := r.Intn(5); edits > 0 && len(result) > 0; edits-- {
+		i := r.Intn(len(result))
+		j := i + r.Intn(len(result)-i+1)
+		switch r.Intn(4) {
+		case 0:
+			r.Read(result[i:j])
+		case 1:
+			inserted := make([]byte, r.Intn(100))
+			r.Read(inserted)
+			result = append(result[:i], append(inserted, result[i:]...)...)
+		case 2:
+			result = append(result[:i], result[j:]...)
+		case 3:
+			moved := append([]byte{}, result[i:j]...)
+			result = append(result[:i], result[j:]...)
+			k := r.Intn(len(result) + 1)
+			result = append(result[:k], append(moved, result[k:]...)...)
+		}
+	}
+	return result
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		source := make([]byte, r.Intn(5000))
+		r.Read(source)
+		target := mutate(r, source)
+		if r.Intn(4) == 0 {
+			source = nil
+		}
+
+		delta := Delta(source, target)
+		result, err := Apply(source, delta)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !bytes.Equal(result, target) {
+			t.Fatalf("Expected the target back, got %v bytes instead of %v", len(result), len(target))
+		}
+	}
+}
+
+func TestDeltaSize(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	source := make([]byte, 100000)
+	r.Read(source)
+
+	for _, test := range []struct {
+		name    string
+		source  []byte
+		target  []byte
+		maxSize int
+	}{
+		{"identical", source, source, 30},
+		{"changed byte", source, append(append(append([]byte{}, source[:50000]...), 'x'), source[50001:]...), 40},
+		{"swapped halves", source, append(append([]byte{}, source[50000:]...), source[:50000]...), 40},
+		{"zeros", nil, make([]byte, 100000), 30},
+	} {
+		delta := Delta(test.source, test.target)
+		if len(delta) > test.maxSize {
+			t.Errorf("Expected a delta of at most %v bytes for %v, got %v", test.maxSize, test.name, len(delta))
+		}
+		if result, err := Apply(test.source, delta); err != nil || !bytes.Equal(result, test.target) {
+			t.Errorf("Expected %v to round trip, got error %v", test.name, err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	// A delta using instructions and address modes that Delta doesn't, in
+	// two windows, the second copying from the first
+	window1 := []byte{
+		22, 0, 2, 6, 4, // Lengths of target window, indicator, sections
+		'x', 'y', // Data
+		163,  // ADD 1, COPY 4 in mode self
+		0, 5, // RUN 5
+		116,        // COPY 4 in mode same 0
+		52,         // COPY 4 in mode near 0
+		36,         // COPY 4 in mode here
+		0, 0, 2, 1, // Addresses
+	}
+	window2 := []byte{
+		3, 0, 0, 2, 1,
+		19, 3, // COPY 3 in mode self
+		1,
+	}
+	delta := []byte{0xD6, 0xC3, 0xC4, 0x00, vcdAppHeader, 3, 'a', 'p', 'p'}
+	delta = append(delta, vcdSource, 10, 0, byte(len(window1)))
+	delta = append(delta, window1...)
+	delta = append(delta, vcdTarget, 5, 0, byte(len(window2)))
+	delta = append(delta, window2...)
+
+	result, err := Apply([]byte("abcdefghij"), delta)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "xabcdyyyyyabcdcdefffffabc"; string(result) != expected {
+		t.Errorf("Expected %v, got %v", expected, string(result))
+	}
+}
+
+// Targets larger than a window are split into several
+func TestDeltaWindows(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	source := make([]byte, 1<<16)
+	r.Read(source)
+	target := bytes.Repeat(source, maxWindowSize/len(source)+2)
+	target[maxWindowSize] ^= 1
+
+	result, err := Apply(source, Delta(source, target))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(result, target) {
+		t.Errorf("Expected the target back, got %v bytes instead of %v", len(result), len(target))
+	}
+}
+
+// A window longer than the maximum is rejected before it is allocated
+func TestApplyLargeWindow(t *testing.T) {
+	// RUN 1 TiB of zeros
+	instructions := appendInt([]byte{0}, 1<<40)
+	window := appendInt(nil, 1<<40)
+	window = append(window, 0, 1, byte(len(instructions)), 0) // Indicator and section lengths
+	window = append(window, 0)                                // Data
+	window = append(window, instructions...)
+	delta := append([]byte{}, magic...)
+	delta = append(delta, 0, 0, byte(len(window)))
+	delta = append(delta, window...)
+
+	if _, err := Apply(nil, delta); err == nil {
+		t.Errorf("Expected an error for a window of 1 TiB")
+	}
+}
+
+func TestApplyCorrupt(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	source := make([]byte, 2000)
+	r.Read(source)
+	delta := Delta(source, mutate(r, source))
+
+	if _, err := Apply(source, []byte("not a delta")); err == nil {
+		t.Errorf("Expected an error for a missing header")
+	}
+	for i := len(magic) + 2; i < len(delta); i++ {
+		if _, err := Apply(source, delta[:i]); err == nil {
+			t.Errorf("Expected an error for a delta truncated to %v bytes", i)
+		}
+	}
+	// Corrupt deltas must not make Apply panic
+	for i := 0; i < 2000; i++ {
+		corrupt := append([]byte{}, delta...)
+		corrupt[len(magic)+1+r.Intn(len(corrupt)-len(magic)-1)] = byte(r.Intn(256))
+		Apply(source, corrupt)
+	}
+}
+
+func TestGitDeltaRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		source := make([]byte, r.Intn(20000))
+		r.Read(source)
+		target := mutate(r, source)
+
+		delta := GitDelta(source, target)
+		result, err := ApplyGitDelta(source, delta)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !bytes.Equal(result, target) {
+			t.Fatalf("Expected the target back, got %v bytes instead of %v", len(result), len(target))
+		}
+	}
+
+	if _, err := ApplyGitDelta([]byte("abc"), GitDelta([]byte("abcd"), nil)); err == nil {
+		t.Errorf("Expected an error for the wrong source")
+	}
+}
@@ -0,0 +1,180 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package bindiff
+
+import (
+	"fmt"
+)
+
+// The most bytes of an insert and of a copy instruction of git's deltas.
+// Copies can be up to 0xFFFFFF bytes, but git itself makes them no longer
+// than this.
+const (
+	gitMaxInsert = 0x7F
+	gitMaxCopy   = 0x10000
+)
+
+// GitDelta returns a delta that turns source into target in the format git
+// uses in packs and binary patches, for ApplyGitDelta. Git's deltas can only
+// copy from the source.
+func GitDelta(source, target []byte) []byte {
+	w := gitDeltaWriter{}
+	w.delta = appendGitInt(w.delta, len(source))
+	w.delta = appendGitInt(w.delta, len(target))
+	findMatches(source, target, false, &w)
+	return w.delta
+}
+
+// appendGitInt appends an integer in git's variable length format, base 128
+// with the least significant digit first, and the top bit set on every byte
+// but the last.
+func appendGitInt(b []byte, n int) []byte {
+	for n >= 0x80 {
+		b = append(b, byte(n)|0x80)
+		n >>= 7
+	}
+	return append(b, byte(n))
+}
+
+// gitDeltaWriter encodes the instructions of a git delta.
+type gitDeltaWriter struct {
+	delta []byte
+}
+
+func (w *gitDeltaWriter) add(b []byte) {
+	for len(b) > 0 {
+		n := len(b)
+		if n > gitMaxInsert {
+			n = gitMaxInsert
+		}
+		w.delta = append(w.delta, byte(n))
+		w.delta = append(w.delta, b[:n]...)
+		b = b[n:]
+	}
+}
+
+func (w *gitDeltaWriter) copy(addr, size int) {
+	for size > 0 {
+		n := size
+		if n > gitMaxCopy {
+			n = gitMaxCopy
+		}
+
+		// The low bits of the opcode say which bytes of the offset and size
+		// follow, least significant first. The rest are zero.
+		i := len(w.delta)
+		opcode := byte(0x80)
+		w.delta = append(w.delta, 0)
+		for k := 0; k < 4; k++ {
+			if b := byte(addr >> (8 * k)); b != 0 {
+				opcode |= 1 << k
+				w.delta = append(w.delta, b)
+			}
+		}
+		// A size of 0x10000 is encoded as 0
+		for k := 0; k < 3 && n != gitMaxCopy; k++ {
+			if b := byte(n >> (8 * k)); b != 0 {
+				opcode |= 0x10 << k
+				w.delta = append(w.delta, b)
+			}
+		}
+		w.delta[i] = opcode
+
+		addr += n
+		size -= n
+	}
+}
+
+// ApplyGitDelta applies a delta in git's format to source and returns the
+// target.
+func ApplyGitDelta(source, delta []byte) ([]byte, error) {
+	r := reader{delta}
+	sourceSize, err := r.gitInt()
+	if err != nil {
+		return nil, err
+	}
+	if sourceSize != len(source) {
+		return nil, fmt.Errorf("delta is for a source of %v bytes, not %v", sourceSize, len(source))
+	}
+	targetSize, err := r.gitInt()
+	if err != nil {
+		return nil, err
+	}
+
+	capacity := targetSize
+	if capacity > maxPreallocation {
+		capacity = maxPreallocation
+	}
+	target := make([]byte, 0, capacity)
+	for len(r.data) > 0 {
+		opcode, _ := r.byte()
+		switch {
+		case opcode&0x80 != 0:
+			var offset, size int
+			for k := 0; k < 4; k++ {
+				if opcode&(1<<k) != 0 {
+					b, err := r.byte()
+					if err != nil {
+						return nil, err
+					}
+					offset |= int(b) << (8 * k)
+				}
+			}
+			for k := 0; k < 3; k++ {
+				if opcode&(0x10<<k) != 0 {
+					b, err := r.byte()
+					if err != nil {
+						return nil, err
+					}
+					size |= int(b) << (8 * k)
+				}
+			}
+			if size == 0 {
+				size = gitMaxCopy
+			}
+			if offset+size > len(source) {
+				return nil, fmt.Errorf("copy of %v bytes at %v is outside the source", size, offset)
+			}
+			if size > targetSize-len(target) {
+				return nil, fmt.Errorf("instructions overflow the target")
+			}
+			target = append(target, source[offset:offset+size]...)
+		case opcode != 0:
+			added, err := r.bytes(int(opcode))
+			if err != nil {
+				return nil, err
+			}
+			if len(added) > targetSize-len(target) {
+				return nil, fmt.Errorf("instructions overflow the target")
+			}
+			target = append(target, added...)
+		default:
+			return nil, fmt.Errorf("unexpected delta opcode 0")
+		}
+	}
+
+	if len(target) != targetSize {
+		return nil, fmt.Errorf("expected a target of %v bytes, got %v", targetSize, len(target))
+	}
+	return target, nil
+}
+
+// gitInt reads an integer written by appendGitInt.
+func (r *reader) gitInt() (int, error) {
+	n := 0
+	for shift := 0; ; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		if shift > 56 {
+			return 0, fmt.Errorf("integer too large")
+		}
+		n |= int(b&0x7F) << shift
+		if b&0x80 == 0 {
+			return n, nil
+		}
+	}
+}
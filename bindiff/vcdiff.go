@@ -0,0 +1,167 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package bindiff
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The header of every VCDIFF delta, section 4.1 of RFC 3284
+var magic = []byte{0xD6, 0xC3, 0xC4, 0x00}
+
+// Bits of the header indicator
+const (
+	vcdDecompress = 0x01 // A secondary compressor was used
+	vcdCodeTable  = 0x02 // The delta has its own code table
+	vcdAppHeader  = 0x04 // Application data follows, an xdelta3 extension
+)
+
+// The most bytes of target a window describes. Delta splits larger targets
+// into several windows, and Apply rejects larger windows.
+const maxWindowSize = 1 << 24
+
+// Bits of the window indicator
+const (
+	vcdSource = 0x01 // The window copies from the source
+	vcdTarget = 0x02 // The window copies from earlier target windows
+)
+
+// Instruction types
+const (
+	instNoop = iota
+	instAdd
+	instRun
+	instCopy
+)
+
+// The sizes of the address caches of the default code table
+const (
+	nearSize = 4
+	sameSize = 3
+)
+
+// An instruction is half of an entry of a code table. A size of 0 means the
+// size follows in the instructions section.
+type instruction struct {
+	kind byte
+	size byte
+	mode byte
+}
+
+// The default code table, section 5.6 of RFC 3284
+var defaultCodeTable = func() (table [256][2]instruction) {
+	i := 0
+	next := func(first, second instruction) {
+		table[i] = [2]instruction{first, second}
+		i++
+	}
+
+	next(instruction{instRun, 0, 0}, instruction{})
+	for size := byte(0); size <= 17; size++ {
+		next(instruction{instAdd, size, 0}, instruction{})
+	}
+	for mode := byte(0); mode < 2+nearSize+sameSize; mode++ {
+		next(instruction{instCopy, 0, mode}, instruction{})
+		for size := byte(4); size <= 18; size++ {
+			next(instruction{instCopy, size, mode}, instruction{})
+		}
+	}
+	for mode := byte(0); mode < 2+nearSize; mode++ {
+		for addSize := byte(1); addSize <= 4; addSize++ {
+			for copySize := byte(4); copySize <= 6; copySize++ {
+				next(instruction{instAdd, addSize, 0}, instruction{instCopy, copySize, mode})
+			}
+		}
+	}
+	for mode := byte(2 + nearSize); mode < 2+nearSize+sameSize; mode++ {
+		for addSize := byte(1); addSize <= 4; addSize++ {
+			next(instruction{instAdd, addSize, 0}, instruction{instCopy, 4, mode})
+		}
+	}
+	for mode := byte(0); mode < 2+nearSize+sameSize; mode++ {
+		next(instruction{instCopy, 4, mode}, instruction{instAdd, 1, 0})
+	}
+	return table
+}()
+
+// Address modes
+const (
+	modeSelf  = 0 // The address itself
+	modeHere  = 1 // The distance back from the current position
+	modeNear  = 2 // The distance from a recent address, for nearSize modes
+	modeSame  = modeNear + nearSize
+	modeCount = modeSame + sameSize
+)
+
+// addressCache remembers recent COPY addresses, so that addresses near or
+// equal to them can be encoded in fewer bytes. Section 5.1 of RFC 3284.
+type addressCache struct {
+	near     [nearSize]int
+	nextNear int
+	same     [sameSize * 256]int
+}
+
+func (c *addressCache) update(addr int) {
+	c.near[c.nextNear] = addr
+	c.nextNear = (c.nextNear + 1) % nearSize
+	c.same[addr%len(c.same)] = addr
+}
+
+// appendInt appends an integer in the variable length format of section 2
+// of RFC 3284: base 128, most significant digit first, with the top bit set
+// on every byte but the last.
+func appendInt(b []byte, n int) []byte {
+	var digits [10]byte
+	i := len(digits) - 1
+	digits[i] = byte(n & 0x7F)
+	for n >>= 7; n > 0; n >>= 7 {
+		i--
+		digits[i] = byte(n&0x7F) | 0x80
+	}
+	return append(b, digits[i:]...)
+}
+
+var errTruncated = errors.New("truncated delta")
+
+// reader reads the sections of a delta.
+type reader struct {
+	data []byte
+}
+
+func (r *reader) byte() (byte, error) {
+	if len(r.data) == 0 {
+		return 0, errTruncated
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b, nil
+}
+
+func (r *reader) int() (int, error) {
+	n := 0
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		if n > (int(^uint(0)>>1))>>7 {
+			return 0, fmt.Errorf("integer too large")
+		}
+		n = n<<7 | int(b&0x7F)
+		if b&0x80 == 0 {
+			return n, nil
+		}
+	}
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data) {
+		return nil, errTruncated
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b, nil
+}
@@ -54,11 +54,11 @@ type DiffMessageDifferentTypes struct {
 }
 
 // DiffDirectories will write messages to ch.
-func DiffDirectories(aFs, bFs fs.FS, callback func(DiffMessage)) {
-	diffDirectories(aFs, bFs, ".", callback)
+func DiffDirectories(aFs, bFs fs.FS, callback func(DiffMessage), opts ...diff.Option) {
+	diffDirectories(aFs, bFs, ".", callback, opts)
 }
 
-func diffDirectories(aFs, bFs fs.FS, commonPath string, callback func(DiffMessage)) {
+func diffDirectories(aFs, bFs fs.FS, commonPath string, callback func(DiffMessage), opts []diff.Option) {
 	aEntries, err := fs.ReadDir(aFs, commonPath)
 	if err != nil {
 		callback(DiffMessageError{
@@ -104,9 +104,9 @@ func diffDirectories(aFs, bFs fs.FS, commonPath string, callback func(DiffMessag
 					BType:       fileType(bEntries[j]),
 				})
 			} else if aIsDir {
-				diffDirectories(aFs, bFs, path.Join(commonPath, aEntries[i].Name()), callback)
+				diffDirectories(aFs, bFs, path.Join(commonPath, aEntries[i].Name()), callback, opts)
 			} else {
-				callback(diffFiles(aFs, bFs, path.Join(commonPath, aEntries[i].Name())))
+				callback(diffFiles(aFs, bFs, path.Join(commonPath, aEntries[i].Name()), opts))
 			}
 			i++
 			j++
@@ -166,7 +166,7 @@ func recursiveListDir(root string) ([]string, error) {
 	return result, err
 }
 
-func diffFiles(aFs, bFs fs.FS, relPath string) DiffMessage {
+func diffFiles(aFs, bFs fs.FS, relPath string, opts []diff.Option) DiffMessage {
 	a, err := aFs.Open(relPath)
 	if err != nil {
 		return DiffMessageError{
@@ -174,16 +174,18 @@ func diffFiles(aFs, bFs fs.FS, relPath string) DiffMessage {
 			Error:       err,
 		}
 	}
+	defer a.Close()
 
-	b, err := aFs.Open(relPath)
+	b, err := bFs.Open(relPath)
 	if err != nil {
 		return DiffMessageError{
 			diffMessage: diffMessage{path: relPath},
 			Error:       err,
 		}
 	}
+	defer b.Close()
 
-	fdiff, err := filediff.DiffFiles(a, b)
+	fdiff, err := filediff.DiffFiles(a, b, opts...)
 	if err != nil {
 		return DiffMessageError{
 			diffMessage: diffMessage{
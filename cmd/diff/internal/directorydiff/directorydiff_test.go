@@ -0,0 +1,45 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package directorydiff
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDiffDirectories(t *testing.T) {
+	aFs := fstest.MapFS{
+		"same.txt":    {Data: []byte("same\n")},
+		"changed.bin": {Data: []byte("a\x00b")},
+		"deleted.txt": {Data: []byte("deleted\n")},
+	}
+	bFs := fstest.MapFS{
+		"same.txt":    {Data: []byte("same\n")},
+		"changed.bin": {Data: []byte("a\x00c")},
+		"added.txt":   {Data: []byte("added\n")},
+	}
+
+	var messages []string
+	DiffDirectories(aFs, bFs, func(msg DiffMessage) {
+		switch msg.(type) {
+		case DiffMessageAdded:
+			messages = append(messages, "added "+msg.Path())
+		case DiffMessageDeleted:
+			messages = append(messages, "deleted "+msg.Path())
+		case DiffMessageModified:
+			messages = append(messages, "modified "+msg.Path())
+		case DiffMessageIdentical:
+			messages = append(messages, "identical "+msg.Path())
+		default:
+			messages = append(messages, "unexpected "+msg.Path())
+		}
+	})
+
+	expected := []string{"added added.txt", "modified changed.bin", "deleted deleted.txt", "identical same.txt"}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("Expected %v, got %v", expected, messages)
+	}
+}
@@ -16,11 +16,12 @@ import (
 )
 
 type FileDiff struct {
-	OriginalInfo, ModifiedInfo fs.FileInfo
-	Diff                       []diff.DiffPart
+	OriginalInfo, ModifiedInfo   fs.FileInfo
+	OriginalLines, ModifiedLines []string // The lines of the files as they are
+	Diff                         []diff.DiffPart
 }
 
-func DiffFiles(a, b fs.File) (FileDiff, error) {
+func DiffFiles(a, b fs.File, opts ...diff.Option) (FileDiff, error) {
 	var err error
 	var aLines, bLines []string
 
@@ -36,7 +37,8 @@ func DiffFiles(a, b fs.File) (FileDiff, error) {
 		return result, err
 	}
 
-	result.Diff = diff.Diff(aLines, bLines)
+	result.OriginalLines, result.ModifiedLines = aLines, bLines
+	result.Diff = diff.Diff(aLines, bLines, opts...)
 
 	return result, nil
 }
@@ -66,6 +68,33 @@ func (f FileDiff) HeaderString(aPath, bPath string) string {
 	)
 }
 
+// GitHeaderString returns the header git puts before a binary patch, with
+// the hashes of the old and new contents for its index line. git apply needs
+// this header, and uses the hashes to check it has the right file.
+func (f FileDiff) GitHeaderString(aPath, bPath, aHash, bHash string) string {
+	aPath, bPath = strings.TrimPrefix(aPath, "/"), strings.TrimPrefix(bPath, "/")
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "diff --git a/%v b/%v\n", aPath, bPath)
+	aMode, bMode := gitMode(f.OriginalInfo), gitMode(f.ModifiedInfo)
+	if aMode == bMode {
+		fmt.Fprintf(&sb, "index %v..%v %v\n", aHash, bHash, aMode)
+	} else {
+		fmt.Fprintf(&sb, "old mode %v\nnew mode %v\n", aMode, bMode)
+		fmt.Fprintf(&sb, "index %v..%v\n", aHash, bHash)
+	}
+	// Without these, git apply can't tell the names apart if they differ
+	fmt.Fprintf(&sb, "--- a/%v\n+++ b/%v\n", aPath, bPath)
+	return sb.String()
+}
+
+// gitMode returns the mode git gives a regular file.
+func gitMode(info fs.FileInfo) string {
+	if info.Mode()&0o111 != 0 {
+		return "100755"
+	}
+	return "100644"
+}
+
 func (f FileDiff) String() string {
 	return patching.DiffString(f.Diff)
 }
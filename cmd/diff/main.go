@@ -6,20 +6,290 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"path"
+	"regexp"
 	"strings"
 
+	"github.com/wk-y/diff"
+	"github.com/wk-y/diff/bindiff"
 	"github.com/wk-y/diff/cmd/diff/internal/directorydiff"
 	"github.com/wk-y/diff/cmd/diff/internal/filediff"
+	"github.com/wk-y/diff/gotokens"
+	"github.com/wk-y/diff/jsondiff"
+	"github.com/wk-y/diff/patching"
 )
 
 var recursive bool
+var algorithm diff.Algorithm
+var wordDiff wordDiffFlag
+var colorMoved bool
+var ignoreCase, ignoreAllSpace, ignoreSpaceChange, ignoreTrailingSpace bool
+var ignoreBlankLines, stripTrailingCR bool
+var ignoreMatching regexpsFlag
+var showSimilarity bool
+var workers int
+var goTokens bool
+var jsonPatch bool
+var vcdiff bool
+var binaryPatch bool
 
 func init() {
 	flag.BoolVar(&recursive, "r", false, "Recurse")
+	flag.Var(&algorithm, "diff-algorithm", "Diff algorithm to use: myers, linear, patience or histogram")
+	flag.Var(&wordDiff, "word-diff", "Show changed words instead of lines, optionally as plain, color or porcelain")
+	flag.BoolVar(&colorMoved, "color-moved", false, "Color the output, showing moved blocks of lines in their own colors")
+	flag.BoolVar(&ignoreCase, "i", false, "Ignore case differences")
+	flag.BoolVar(&ignoreAllSpace, "w", false, "Ignore all white space")
+	flag.BoolVar(&ignoreSpaceChange, "b", false, "Ignore changes in the amount of white space")
+	flag.BoolVar(&ignoreTrailingSpace, "Z", false, "Ignore white space at line end")
+	flag.BoolVar(&ignoreBlankLines, "B", false, "Ignore changes where lines are all blank")
+	flag.BoolVar(&stripTrailingCR, "strip-trailing-cr", false, "Strip trailing carriage return on input")
+	flag.Var(&ignoreMatching, "I", "Ignore changes where all lines match `RE`, can be given more than once")
+	flag.Var(&ignoreMatching, "ignore-matching-lines", "Same as -I")
+	flag.IntVar(&workers, "workers", 1, "Number of goroutines the linear, patience and histogram algorithms can use")
+	flag.BoolVar(&goTokens, "go-tokens", false, "Diff Go source token by token, ignoring formatting, and show changed line:column ranges")
+	flag.BoolVar(&jsonPatch, "json", false, "Compare JSON documents structurally, and show the differences as a JSON Patch")
+	flag.BoolVar(&vcdiff, "vcdiff", false, "Write an RFC 3284 VCDIFF delta from FILE1 to FILE2, for binary files. With -r, binary files are shown like -binary")
+	flag.BoolVar(&binaryPatch, "binary", false, "Show changes to binary files as git binary patches")
+	flag.BoolVar(&showSimilarity, "similarity", false, "Show how similar the files are, as a percentage of lines matched")
+}
+
+// regexpsFlag collects the patterns of a flag that can be repeated.
+type regexpsFlag []*regexp.Regexp
+
+func (r *regexpsFlag) String() string {
+	if r == nil {
+		return ""
+	}
+	patterns := make([]string, len(*r))
+	for i, pattern := range *r {
+		patterns[i] = pattern.String()
+	}
+	return strings.Join(patterns, ", ")
+}
+
+func (r *regexpsFlag) Set(value string) error {
+	pattern, err := regexp.Compile(value)
+	if err != nil {
+		return err
+	}
+	*r = append(*r, pattern)
+	return nil
+}
+
+// wordDiffFlag is a flag that can be given either alone or with a format,
+// like git's --word-diff[=<mode>].
+type wordDiffFlag struct {
+	enabled bool
+	format  patching.WordDiffFormat
+}
+
+func (w *wordDiffFlag) String() string {
+	if w == nil || !w.enabled {
+		return ""
+	}
+	return w.format.String()
+}
+
+func (w *wordDiffFlag) Set(value string) error {
+	switch value {
+	case "true":
+		w.enabled = true
+		w.format = patching.WordDiffPlain
+	case "false":
+		w.enabled = false
+	default:
+		w.enabled = true
+		return w.format.Set(value)
+	}
+	return nil
+}
+
+func (w *wordDiffFlag) IsBoolFlag() bool {
+	return true
+}
+
+// diffOptions returns the diff options selected by the flags.
+func diffOptions() []diff.Option {
+	opts := []diff.Option{diff.WithAlgorithm(algorithm), diff.WithWorkers(workers)}
+	for _, option := range []struct {
+		enabled bool
+		option  func() diff.Option
+	}{
+		{ignoreCase, diff.WithIgnoreCase},
+		{ignoreAllSpace, diff.WithIgnoreAllSpace},
+		{ignoreSpaceChange, diff.WithIgnoreSpaceChange},
+		{ignoreTrailingSpace, diff.WithIgnoreTrailingSpace},
+		{stripTrailingCR, diff.WithStripTrailingCR},
+	} {
+		if option.enabled {
+			opts = append(opts, option.option())
+		}
+	}
+	return opts
+}
+
+// similarityHeader returns a line like git's "similarity index" header if
+// -similarity was given.
+func similarityHeader(fdiff filediff.FileDiff) string {
+	if !showSimilarity {
+		return ""
+	}
+	ratio := diff.SimilarityFromParts(fdiff.Diff).Ratio()
+	return fmt.Sprintf("similarity index %v%%\n", int(ratio*100))
+}
+
+// diffBody formats the hunks of a file diff. With -json, it formats a JSON
+// Patch instead if both files are JSON, and isJSONPatch is true.
+func diffBody(fdiff filediff.FileDiff) (body string, isJSONPatch bool) {
+	if jsonPatch {
+		body, err := jsonBody(fdiff)
+		if err == nil {
+			return body, true
+		}
+		fmt.Fprintf(os.Stderr, "Failed to parse JSON, showing a line diff: %v\n", err)
+	}
+	if goTokens {
+		body, err := goTokenBody(fdiff)
+		if err == nil {
+			return body, false
+		}
+		fmt.Fprintf(os.Stderr, "Failed to tokenize Go source, showing a line diff: %v\n", err)
+	}
+
+	d := fdiff.Diff
+	if colorMoved && !wordDiff.enabled {
+		d, _ = diff.DetectMoves(d)
+	}
+
+	hunkOpts := []patching.HunkOption{patching.WithIgnoreMatchingLines(ignoreMatching...)}
+	if ignoreBlankLines {
+		hunkOpts = append(hunkOpts, patching.WithIgnoreBlankLines(diffOptions()...))
+	}
+
+	var sb strings.Builder
+	for _, hunk := range patching.HunkDiff(d, hunkOpts...) {
+		switch {
+		case wordDiff.enabled:
+			sb.WriteString(hunk.WordDiffString(wordDiff.format))
+		case colorMoved:
+			sb.WriteString(hunk.ColorString())
+		default:
+			sb.WriteString(hunk.String())
+		}
+	}
+	return sb.String(), false
+}
+
+// goTokenBody formats a token diff of the files of a file diff. Each change
+// has a header with the line:column ranges it covers, followed by the
+// removed and added source text.
+func goTokenBody(fdiff filediff.FileDiff) (string, error) {
+	aSource, bSource := sources(fdiff)
+
+	changes, err := gotokens.Diff([]byte(aSource), []byte(bSource), diff.WithAlgorithm(algorithm), diff.WithWorkers(workers))
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, change := range changes {
+		fmt.Fprintf(&sb, "@@ -%v +%v @@\n", change.A, change.B)
+		for _, side := range []struct {
+			prefix string
+			text   string
+		}{
+			{"-", aSource[change.A.Start.Offset:change.A.End.Offset]},
+			{"+", bSource[change.B.Start.Offset:change.B.End.Offset]},
+		} {
+			if side.text == "" {
+				continue
+			}
+			for _, line := range strings.Split(side.text, "\n") {
+				sb.WriteString(side.prefix + line + "\n")
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// jsonBody formats a JSON Patch turning the first file of a file diff into
+// the second, with an operation on each line. It is empty if the documents
+// are the same.
+func jsonBody(fdiff filediff.FileDiff) (string, error) {
+	aSource, bSource := sources(fdiff)
+	var a, b interface{}
+	for _, doc := range []struct {
+		source string
+		value  *interface{}
+	}{{aSource, &a}, {bSource, &b}} {
+		decoder := json.NewDecoder(strings.NewReader(doc.source))
+		// Keep numbers as written, so that large integers aren't rounded
+		decoder.UseNumber()
+		if err := decoder.Decode(doc.value); err != nil {
+			return "", err
+		}
+		if decoder.More() {
+			return "", fmt.Errorf("unexpected data after the JSON value")
+		}
+	}
+
+	patch := jsondiff.Diff(a, b, diff.WithAlgorithm(algorithm), diff.WithWorkers(workers))
+	if len(patch) == 0 {
+		return "", nil
+	}
+	var sb strings.Builder
+	sb.WriteString("[\n")
+	for i, op := range patch {
+		var encoded bytes.Buffer
+		encoder := json.NewEncoder(&encoded)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(op); err != nil {
+			return "", err
+		}
+		sb.WriteString("  " + strings.TrimSuffix(encoded.String(), "\n"))
+		if i < len(patch)-1 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("]\n")
+	return sb.String(), nil
+}
+
+// isBinary reports whether either file of a file diff is binary. Uses the
+// strategy of checking for null byte
+// https://www.gnu.org/software/diffutils/manual/html_node/Binary.html
+func isBinary(fdiff filediff.FileDiff) bool {
+	for _, line := range fdiff.Diff {
+		if strings.IndexByte(line.Value, 0) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// binaryBody formats a git binary patch between the files of a file diff,
+// with git's header, or nothing if they are the same.
+func binaryBody(fdiff filediff.FileDiff, aPath, bPath string) string {
+	a, b := sources(fdiff)
+	if a == b {
+		return ""
+	}
+	p := patching.BinaryDiff([]byte(a), []byte(b))
+	return fdiff.GitHeaderString(aPath, bPath, p.OldHash, p.NewHash) + p.String()
+}
+
+// sources puts the files of a file diff back together. They come from the
+// lines as read, since with options like -i the identical parts of the diff
+// only hold the lines of the first file.
+func sources(fdiff filediff.FileDiff) (a, b string) {
+	return strings.Join(fdiff.OriginalLines, ""), strings.Join(fdiff.ModifiedLines, "")
 }
 
 func main() {
@@ -31,6 +301,14 @@ func main() {
 
 	a := flag.Arg(0)
 	b := flag.Arg(1)
+	opts := diffOptions()
+	if vcdiff && !recursive {
+		if err := writeDelta(a, b); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to calculate delta: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 	if recursive {
 		callback := func(msg directorydiff.DiffMessage) {
 			switch msg := msg.(type) {
@@ -41,24 +319,19 @@ func main() {
 				parent, file := path.Split(path.Join(a, msg.Path()))
 				fmt.Printf("Only in %v: %v\n", strings.TrimSuffix(parent, "/"), file)
 			case directorydiff.DiffMessageModified:
-				// Is the file a binary? Uses the strategy of checking for null byte
-				// https://www.gnu.org/software/diffutils/manual/html_node/Binary.html
-				isBinary := false
-				for _, line := range msg.FileDiff.Diff {
-					for _, c := range []byte(line.Value) {
-						if c == 0 {
-							isBinary = true
-							break
-						}
-					}
-					if isBinary {
-						break
-					}
-				}
-				if isBinary {
-					fmt.Printf("Binary files %v and %v differ\n", path.Join(a, msg.Path()), path.Join(b, msg.Path()))
-				} else {
-					fmt.Print(msg.FileDiff)
+				aPath, bPath := path.Join(a, msg.Path()), path.Join(b, msg.Path())
+				switch {
+				case !isBinary(msg.FileDiff):
+					fmt.Print(textDiff(msg.FileDiff, aPath, bPath))
+				case binaryPatch || vcdiff:
+					// A raw delta can't be told apart from the rest of the
+					// output, so -vcdiff uses git binary patches here, which
+					// have a header for each file. Like git, the paths are
+					// relative to the directories, so that after its a/ and
+					// b/ prefixes they line up with the other headers
+					fmt.Print(binaryBody(msg.FileDiff, msg.Path(), msg.Path()))
+				default:
+					fmt.Printf("Binary files %v and %v differ\n", aPath, bPath)
 				}
 			case directorydiff.DiffMessageDifferentTypes:
 				fmt.Printf("File %v is %v while file %v is a %v\n", path.Join(a, msg.Path()), msg.AType, path.Join(b, msg.Path()), msg.BType)
@@ -75,19 +348,38 @@ func main() {
 			os.DirFS(path.Join(wd, a)),
 			os.DirFS(path.Join(wd, b)),
 			callback,
+			opts...,
 		)
 	} else {
-		fdiff, err := diffSingle(a, b)
+		fdiff, err := diffSingle(a, b, opts)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to calculate diff: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Print(fdiff.HeaderString(a, b))
-		fmt.Print(fdiff)
+		if binaryPatch && isBinary(fdiff) {
+			fmt.Print(binaryBody(fdiff, a, b))
+			return
+		}
+		fmt.Print(textDiff(fdiff, a, b))
 	}
 }
 
-func diffSingle(a, b string) (filediff.FileDiff, error) {
+// textDiff formats the changes to a file that isn't shown as binary, with
+// its headers.
+func textDiff(fdiff filediff.FileDiff, aPath, bPath string) string {
+	// Like GNU diff, there is no header when -B leaves nothing to show
+	body, isJSONPatch := diffBody(fdiff)
+	if body == "" {
+		return ""
+	}
+	// A JSON Patch is shown alone, so that it can be used as it is
+	if isJSONPatch {
+		return body
+	}
+	return similarityHeader(fdiff) + fdiff.HeaderString(aPath, bPath) + body
+}
+
+func diffSingle(a, b string, opts []diff.Option) (filediff.FileDiff, error) {
 	aFile, err := os.Open(a)
 	if err != nil {
 		return filediff.FileDiff{}, err
@@ -100,5 +392,19 @@ func diffSingle(a, b string) (filediff.FileDiff, error) {
 	}
 	defer bFile.Close()
 
-	return filediff.DiffFiles(aFile, bFile)
+	return filediff.DiffFiles(aFile, bFile, opts...)
+}
+
+// writeDelta writes a VCDIFF delta from file a to file b to stdout.
+func writeDelta(a, b string) error {
+	source, err := os.ReadFile(a)
+	if err != nil {
+		return err
+	}
+	target, err := os.ReadFile(b)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(bindiff.Delta(source, target))
+	return err
 }
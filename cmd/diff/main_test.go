@@ -0,0 +1,117 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wk-y/diff"
+)
+
+func TestGoTokenBodyIgnoreCase(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	if err := os.WriteFile(a, []byte("package p\n\nvar X = Foo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("package p\n\nvar X = foo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// -i makes the lines identical, but the tokens still differ
+	fdiff, err := diffSingle(a, b, []diff.Option{diff.WithIgnoreCase()})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	body, err := goTokenBody(fdiff)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "@@ -3:9-3:12 +3:9-3:12 @@\n-Foo\n+foo\n"; body != expected {
+		t.Errorf("Expected %q, got %q", expected, body)
+	}
+}
+
+// A JSON Patch is shown without headers, but a line diff of files that aren't
+// JSON still has them
+func TestTextDiffJSONPatch(t *testing.T) {
+	jsonPatch, showSimilarity = true, true
+	defer func() {
+		jsonPatch, showSimilarity = false, false
+	}()
+
+	dir := t.TempDir()
+	for _, testCase := range []struct {
+		a, b     string
+		expected string
+	}{
+		{`{"a":1}`, `{"a":2}`, "[\n  {\"op\":\"replace\",\"path\":\"/a\",\"value\":2}\n]\n"},
+		{"[1\n", "[2\n", "similarity index 0%\n"},
+	} {
+		a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+		if err := os.WriteFile(a, []byte(testCase.a), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(b, []byte(testCase.b), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		fdiff, err := diffSingle(a, b, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result := textDiff(fdiff, a, b); !strings.HasPrefix(result, testCase.expected) {
+			t.Errorf("Expected %q to start with %q", result, testCase.expected)
+		}
+	}
+}
+
+// git apply should accept the binary patches -binary makes
+func TestBinaryBodyGitApply(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	dir := t.TempDir()
+	old := bytes.Repeat([]byte("\x00binary\x01"), 1000)
+	for _, testCase := range []struct {
+		name     string
+		contents []byte
+	}{
+		{"a.bin", old},
+		{"b.bin", append(append([]byte{}, old[:500]...), "\x00changed"...)},
+		{"small.bin", []byte("\x00small")},
+	} {
+		if err := os.WriteFile(filepath.Join(dir, testCase.name), testCase.contents, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, names := range [][2]string{
+		{"a.bin", "b.bin"},
+		{"b.bin", "a.bin"},
+		{"a.bin", "small.bin"},
+	} {
+		fdiff, err := diffSingle(filepath.Join(dir, names[0]), filepath.Join(dir, names[1]), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, bPath := range []string{names[1], names[0]} {
+			patch := filepath.Join(dir, "patch")
+			if err := os.WriteFile(patch, []byte(binaryBody(fdiff, names[0], bPath)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cmd := exec.Command("git", "apply", "--check", patch)
+			cmd.Dir = dir
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("git apply rejected the patch from %v to %v: %v\n%s", names[0], bPath, err, output)
+			}
+		}
+	}
+}
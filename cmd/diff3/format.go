@@ -0,0 +1,196 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wk-y/diff/merge"
+)
+
+// normalString formats the changed regions like GNU diff3 does without
+// options. The regions are of a merge of MYFILE and OLDFILE with YOURFILE as
+// the base. Files are numbered 1 for MYFILE, 2 for OLDFILE and 3 for
+// YOURFILE, and a header of ====N means that only file N differs.
+func normalString(regions []merge.Region) string {
+	var sb strings.Builder
+	for _, r := range regions {
+		// The order to show the files in, and which file's lines are left
+		// out because they are the same as the next file's
+		order := []int{0, 1, 2}
+		skipped := 0
+		switch r.Kind {
+		case merge.Unchanged:
+			continue
+		case merge.ChangedOurs:
+			sb.WriteString("====1\n")
+			skipped = 1
+		case merge.ChangedTheirs:
+			sb.WriteString("====2\n")
+			order = []int{0, 2, 1}
+		case merge.ChangedBoth:
+			sb.WriteString("====3\n")
+		case merge.Conflict:
+			sb.WriteString("====\n")
+			skipped = -1
+		}
+
+		starts := []int{r.OursStart, r.TheirsStart, r.BaseStart}
+		lines := [][]string{r.Ours, r.Theirs, r.Base}
+		for _, file := range order {
+			start, count := starts[file], len(lines[file])
+			if count == 0 {
+				fmt.Fprintf(&sb, "%v:%va\n", file+1, start)
+			} else {
+				fmt.Fprintf(&sb, "%v:%vc\n", file+1, lineRange(start, count))
+			}
+			if file == skipped {
+				continue
+			}
+			for _, line := range lines[file] {
+				sb.WriteString("  " + line)
+				if !strings.HasSuffix(line, "\n") {
+					sb.WriteString("\n\\ No newline at end of file\n")
+				}
+			}
+		}
+	}
+	return sb.String()
+}
+
+// lineRange formats count lines starting at index start the way ed and diff
+// do.
+func lineRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%v,%v", start+1, start+count)
+}
+
+// edScript returns an ed script that incorporates the changes selected by
+// mode into MYFILE, and whether any conflicts were bracketed. The commands
+// go from the end of the file to the start, so that the line numbers of
+// each command are not affected by the ones before it.
+func edScript(regions []merge.Region, mode mergeMode, names [3]string) (string, bool) {
+	var sb strings.Builder
+	conflicts := false
+	for k := len(regions) - 1; k >= 0; k-- {
+		r := regions[k]
+		start, end := r.OursStart, r.OursStart+len(r.Ours)
+		switch {
+		case r.Kind == merge.ChangedTheirs && mode.theirs,
+			r.Kind == merge.Conflict && mode.conflicts == conflictsReplaced:
+			switch {
+			case len(r.Ours) == 0 && len(r.Theirs) == 0:
+			case len(r.Ours) == 0:
+				fmt.Fprintf(&sb, "%va\n", start)
+				writeEdLines(&sb, r.Theirs, start+1)
+			case len(r.Theirs) == 0:
+				fmt.Fprintf(&sb, "%vd\n", lineRange(start, len(r.Ours)))
+			default:
+				fmt.Fprintf(&sb, "%vc\n", lineRange(start, len(r.Ours)))
+				writeEdLines(&sb, r.Theirs, start+1)
+			}
+
+		case r.Kind == merge.Conflict && mode.conflicts == conflictsBracketed:
+			// Our lines are already there, so only the markers and their
+			// lines are added around them
+			after := []string{}
+			if mode.showAll {
+				after = append(after, marker("|||||||", names[1]))
+				after = append(after, r.Base...)
+			}
+			after = append(after, "=======\n")
+			after = append(after, r.Theirs...)
+			after = append(after, marker(">>>>>>>", names[2]))
+			fmt.Fprintf(&sb, "%va\n", end)
+			writeEdLines(&sb, after, end+1)
+			fmt.Fprintf(&sb, "%va\n", start)
+			writeEdLines(&sb, []string{marker("<<<<<<<", names[0])}, start+1)
+			conflicts = true
+
+		case r.Kind == merge.ChangedBoth && mode.showAll:
+			// Like GNU diff3, show the change as OLDFILE against YOURFILE
+			fmt.Fprintf(&sb, "%va\n", end)
+			writeEdLines(&sb, []string{marker(">>>>>>>", names[2])}, end+1)
+			before := []string{marker("<<<<<<<", names[1])}
+			before = append(before, r.Base...)
+			before = append(before, "=======\n")
+			fmt.Fprintf(&sb, "%va\n", start)
+			writeEdLines(&sb, before, start+1)
+			conflicts = true
+		}
+	}
+	return sb.String(), conflicts
+}
+
+// writeEdLines writes the text of an a or c command, which will be lines
+// start onwards once the command has run. Since a line of just "." would end
+// the text, lines starting with "." get another one, which is then removed
+// with an s command.
+func writeEdLines(sb *strings.Builder, lines []string, start int) {
+	dotted := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, ".") {
+			dotted = true
+			sb.WriteString(".")
+		}
+		sb.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	sb.WriteString(".\n")
+	if dotted {
+		fmt.Fprintf(sb, "%vs/^\\.//\n", lineRange(start-1, len(lines)))
+	}
+}
+
+func marker(marker, label string) string {
+	return marker + " " + label + "\n"
+}
+
+// mergedString returns MYFILE with the changes selected by mode
+// incorporated, and whether any conflicts were bracketed.
+func mergedString(regions []merge.Region, mode mergeMode, names [3]string) (string, bool) {
+	var sb strings.Builder
+	conflicts := false
+	for _, r := range regions {
+		switch {
+		case r.Kind == merge.ChangedTheirs && !mode.theirs,
+			r.Kind == merge.Conflict && mode.conflicts == conflictsKept:
+			sb.WriteString(strings.Join(r.Ours, ""))
+
+		case r.Kind == merge.Conflict && mode.conflicts == conflictsReplaced:
+			sb.WriteString(strings.Join(r.Theirs, ""))
+
+		case r.Kind == merge.Conflict:
+			style := merge.StyleMerge
+			if mode.showAll {
+				style = merge.StyleDiff3
+			}
+			sb.WriteString(merge.Render([]merge.Region{r}, style, merge.Labels{
+				Ours:   names[0],
+				Base:   names[1],
+				Theirs: names[2],
+			}))
+			conflicts = true
+
+		case r.Kind == merge.ChangedBoth && mode.showAll:
+			// Like GNU diff3, show the change as OLDFILE against YOURFILE
+			sb.WriteString(merge.Render([]merge.Region{{
+				Kind:   merge.Conflict,
+				Ours:   r.Base,
+				Theirs: r.Theirs,
+			}}, merge.StyleMerge, merge.Labels{Ours: names[1], Theirs: names[2]}))
+			conflicts = true
+
+		default:
+			sb.WriteString(strings.Join(r.Merged(), ""))
+		}
+	}
+	return sb.String(), conflicts
+}
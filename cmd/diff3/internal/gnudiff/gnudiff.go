@@ -0,0 +1,555 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package gnudiff diffs lines the way GNU diff does, so that diff3 can line
+// files up the same way as GNU diff3.
+package gnudiff
+
+import (
+	"github.com/wk-y/diff"
+)
+
+// The number of identical lines GNU diff3 keeps around the changes when it
+// runs diff, with --horizon-lines=100. Identical lines further away than
+// this are left out of the comparison.
+const horizonLines = 100
+
+// Diff returns the diff from a to b that GNU diff finds. Within each run of
+// changes, removals are placed before additions.
+func Diff(a, b []string) []diff.DiffPart {
+	ids := map[string]int{}
+	id := func(line string) int {
+		if _, ok := ids[line]; !ok {
+			ids[line] = len(ids)
+		}
+		return ids[line]
+	}
+	aIDs := make([]int, len(a))
+	for i, line := range a {
+		aIDs[i] = id(line)
+	}
+	bIDs := make([]int, len(b))
+	for j, line := range b {
+		bIDs[j] = id(line)
+	}
+
+	d := make([]diff.DiffPart, 0, len(a)+len(b))
+	var i, j int
+	for _, action := range actions(aIDs, bIDs) {
+		switch action {
+		case diff.DiffIdentical:
+			d = append(d, diff.DiffPart{Action: action, Value: a[i]})
+			i++
+			j++
+		case diff.DiffRemoved:
+			d = append(d, diff.DiffPart{Action: action, Value: a[i]})
+			i++
+		case diff.DiffAdded:
+			d = append(d, diff.DiffPart{Action: action, Value: b[j]})
+			j++
+		}
+	}
+	return d
+}
+
+// actions diffs a and b like GNU diff (analyze.c and diffseq.h). Where
+// several edit scripts have the same cost it picks the same one as GNU diff:
+// lines with no match in the other input, and runs of lines with many
+// matches, are discarded before looking for the middle snake of the rest,
+// and afterwards each run of changes is slid to merge with the runs around
+// it. Like GNU diff, it gives up on a minimal script for expensive inputs.
+func actions(a, b []int) []diff.DiffAction {
+	// Only the lines within the horizon of the changes are compared
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	skipPrefix, skipSuffix := 0, 0
+	if prefix > horizonLines {
+		skipPrefix = prefix - horizonLines
+	}
+	if suffix > horizonLines {
+		skipSuffix = suffix - horizonLines
+	}
+	g := differ{
+		a: a[skipPrefix : len(a)-skipSuffix],
+		b: b[skipPrefix : len(b)-skipSuffix],
+	}
+	g.compare()
+
+	d := make([]diff.DiffAction, 0, len(a)+len(b))
+	for k := 0; k < skipPrefix; k++ {
+		d = append(d, diff.DiffIdentical)
+	}
+	for i, j := 0, 0; i < len(g.a) || j < len(g.b); {
+		switch {
+		case i < len(g.a) && g.aChanged[i+1]:
+			d = append(d, diff.DiffRemoved)
+			i++
+		case j < len(g.b) && g.bChanged[j+1]:
+			d = append(d, diff.DiffAdded)
+			j++
+		default:
+			d = append(d, diff.DiffIdentical)
+			i++
+			j++
+		}
+	}
+	for k := 0; k < skipSuffix; k++ {
+		d = append(d, diff.DiffIdentical)
+	}
+	return d
+}
+
+type differ struct {
+	a, b []int
+	// Whether each line is changed, offset by one so that there is an
+	// unchanged line before and after each input
+	aChanged, bChanged []bool
+
+	// The lines left after discarding, and where they are in a and b
+	xv, yv         []int
+	xIndex, yIndex []int
+	fd, bd         []int // Furthest reaching x for each diagonal
+	offset         int   // Where diagonal 0 is in fd and bd
+	tooExpensive   int
+}
+
+func (g *differ) compare() {
+	g.aChanged = make([]bool, len(g.a)+2)
+	g.bChanged = make([]bool, len(g.b)+2)
+	g.discardConfusingLines()
+
+	// GNU diff gives up on finding a minimal script after about the square
+	// root of the size of the input, but no sooner than 4096 changes
+	diags := len(g.xv) + len(g.yv) + 3
+	g.tooExpensive = 1
+	for ; diags != 0; diags >>= 2 {
+		g.tooExpensive <<= 1
+	}
+	if g.tooExpensive < 4096 {
+		g.tooExpensive = 4096
+	}
+	g.offset = len(g.yv) + 1
+	g.fd = make([]int, len(g.xv)+len(g.yv)+3)
+	g.bd = make([]int, len(g.xv)+len(g.yv)+3)
+
+	g.compareSeq(0, len(g.xv), 0, len(g.yv), false)
+	g.shiftBoundaries(g.a, g.aChanged, g.bChanged)
+	g.shiftBoundaries(g.b, g.bChanged, g.aChanged)
+}
+
+// discardConfusingLines marks lines that match no line of the other input
+// as changed, and leaves them out of xv and yv along with runs of lines that
+// match many lines, as these only slow down the search and lead it astray.
+func (g *differ) discardConfusingLines() {
+	ids := 0
+	for _, lines := range [][]int{g.a, g.b} {
+		for _, id := range lines {
+			if id >= ids {
+				ids = id + 1
+			}
+		}
+	}
+	aCount, bCount := make([]int, ids), make([]int, ids)
+	for _, id := range g.a {
+		aCount[id]++
+	}
+	for _, id := range g.b {
+		bCount[id]++
+	}
+
+	for _, side := range []struct {
+		lines   []int
+		counts  []int // In the other input
+		changed []bool
+		v       *[]int
+		index   *[]int
+	}{
+		{g.a, bCount, g.aChanged, &g.xv, &g.xIndex},
+		{g.b, aCount, g.bChanged, &g.yv, &g.yIndex},
+	} {
+		discards := discardable(side.lines, side.counts)
+		for i, id := range side.lines {
+			if discards[i] == 0 {
+				*side.v = append(*side.v, id)
+				*side.index = append(*side.index, i)
+			} else {
+				side.changed[i+1] = true
+			}
+		}
+	}
+}
+
+// discardable returns 1 for each line to discard, 2 for lines that might be
+// discarded, and 0 for lines to keep.
+func discardable(lines, counts []int) []int {
+	discards := make([]int, len(lines))
+
+	// Lines with more matches than about the square root of the number of
+	// lines might be discarded
+	many := 5
+	for tem := len(lines) / 64; ; {
+		if tem >>= 2; tem <= 0 {
+			break
+		}
+		many *= 2
+	}
+	for i, id := range lines {
+		switch n := counts[id]; {
+		case n == 0:
+			discards[i] = 1
+		case n > many:
+			discards[i] = 2
+		}
+	}
+
+	// Only discard those lines in the middle of a run of discarded lines
+	end := len(lines)
+	for i := 0; i < end; i++ {
+		if discards[i] == 2 {
+			discards[i] = 0
+			continue
+		}
+		if discards[i] == 0 {
+			continue
+		}
+
+		// The end of this run, and how many of its lines might be discarded
+		j := i
+		provisional := 0
+		for ; j < end && discards[j] != 0; j++ {
+			if discards[j] == 2 {
+				provisional++
+			}
+		}
+		for j > i && discards[j-1] == 2 {
+			j--
+			discards[j] = 0
+			provisional--
+		}
+		length := j - i
+
+		// If a quarter of the run might be discarded, keep all of those
+		if provisional*4 > length {
+			for j > i {
+				j--
+				if discards[j] == 2 {
+					discards[j] = 0
+				}
+			}
+			i = j
+			continue
+		}
+
+		// Keep runs of about the square root of a quarter of the length
+		minimum := 1
+		for tem := length >> 2; ; {
+			if tem >>= 2; tem <= 0 {
+				break
+			}
+			minimum <<= 1
+		}
+		minimum++
+		consec := 0
+		for j := 0; j < length; j++ {
+			switch {
+			case discards[i+j] != 2:
+				consec = 0
+			case minimum == consec+1:
+				consec++
+				// Back up to the start of the run to keep all of it
+				j -= consec
+			case minimum < consec+1:
+				consec++
+				discards[i+j] = 0
+			default:
+				consec++
+			}
+		}
+
+		// Keep the lines that might be discarded at either end of the run,
+		// until 3 discarded lines in a row, or a discarded line 8 lines in
+		consec = 0
+		for j := 0; j < length; j++ {
+			if j >= 8 && discards[i+j] == 1 {
+				break
+			}
+			switch discards[i+j] {
+			case 2:
+				consec = 0
+				discards[i+j] = 0
+			case 0:
+				consec = 0
+			default:
+				consec++
+			}
+			if consec == 3 {
+				break
+			}
+		}
+		i += length - 1
+		consec = 0
+		for j := 0; j < length; j++ {
+			if j >= 8 && discards[i-j] == 1 {
+				break
+			}
+			switch discards[i-j] {
+			case 2:
+				consec = 0
+				discards[i-j] = 0
+			case 0:
+				consec = 0
+			default:
+				consec++
+			}
+			if consec == 3 {
+				break
+			}
+		}
+	}
+	return discards
+}
+
+// compareSeq marks the changes between xv[xOff:xLim] and yv[yOff:yLim],
+// splitting the problem at a point in the middle of a minimal path.
+func (g *differ) compareSeq(xOff, xLim, yOff, yLim int, findMinimal bool) {
+	for xOff < xLim && yOff < yLim && g.xv[xOff] == g.yv[yOff] {
+		xOff++
+		yOff++
+	}
+	for xOff < xLim && yOff < yLim && g.xv[xLim-1] == g.yv[yLim-1] {
+		xLim--
+		yLim--
+	}
+
+	switch {
+	case xOff == xLim:
+		for ; yOff < yLim; yOff++ {
+			g.bChanged[g.yIndex[yOff]+1] = true
+		}
+	case yOff == yLim:
+		for ; xOff < xLim; xOff++ {
+			g.aChanged[g.xIndex[xOff]+1] = true
+		}
+	default:
+		xMid, yMid, loMinimal, hiMinimal := g.diag(xOff, xLim, yOff, yLim, findMinimal)
+		g.compareSeq(xOff, xMid, yOff, yMid, loMinimal)
+		g.compareSeq(xMid, xLim, yMid, yLim, hiMinimal)
+	}
+}
+
+// diag searches forwards from the start and backwards from the end at once,
+// and returns where the paths meet. If this takes too long, it settles for
+// the point that got furthest, and reports which side of it may not be
+// minimal.
+func (g *differ) diag(xOff, xLim, yOff, yLim int, findMinimal bool) (xMid, yMid int, loMinimal, hiMinimal bool) {
+	const maxInt = int(^uint(0) >> 1)
+	o := g.offset
+	fd, bd := g.fd, g.bd
+	dMin, dMax := xOff-yLim, xLim-yOff
+	fMid, bMid := xOff-yOff, xLim-yLim
+	fMin, fMax := fMid, fMid
+	bMin, bMax := bMid, bMid
+	odd := (fMid-bMid)&1 != 0
+
+	fd[o+fMid] = xOff
+	bd[o+bMid] = xLim
+
+	for c := 1; ; c++ {
+		if fMin > dMin {
+			fMin--
+			fd[o+fMin-1] = -1
+		} else {
+			fMin++
+		}
+		if fMax < dMax {
+			fMax++
+			fd[o+fMax+1] = -1
+		} else {
+			fMax--
+		}
+		for d := fMax; d >= fMin; d -= 2 {
+			tlo, thi := fd[o+d-1], fd[o+d+1]
+			x := tlo + 1
+			if tlo < thi {
+				x = thi
+			}
+			y := x - d
+			for x < xLim && y < yLim && g.xv[x] == g.yv[y] {
+				x++
+				y++
+			}
+			fd[o+d] = x
+			if odd && bMin <= d && d <= bMax && bd[o+d] <= x {
+				return x, y, true, true
+			}
+		}
+
+		if bMin > dMin {
+			bMin--
+			bd[o+bMin-1] = maxInt
+		} else {
+			bMin++
+		}
+		if bMax < dMax {
+			bMax++
+			bd[o+bMax+1] = maxInt
+		} else {
+			bMax--
+		}
+		for d := bMax; d >= bMin; d -= 2 {
+			tlo, thi := bd[o+d-1], bd[o+d+1]
+			x := thi - 1
+			if tlo < thi {
+				x = tlo
+			}
+			y := x - d
+			for xOff < x && yOff < y && g.xv[x-1] == g.yv[y-1] {
+				x--
+				y--
+			}
+			bd[o+d] = x
+			if !odd && fMin <= d && d <= fMax && x <= fd[o+d] {
+				return x, y, true, true
+			}
+		}
+
+		if findMinimal {
+			continue
+		}
+		if c < g.tooExpensive {
+			continue
+		}
+
+		// Give up, and use the forward diagonal that got furthest or the
+		// backward one that did, whichever is better
+		fxyBest, fxBest := -1, 0
+		for d := fMax; d >= fMin; d -= 2 {
+			x := fd[o+d]
+			if x > xLim {
+				x = xLim
+			}
+			y := x - d
+			if yLim < y {
+				x, y = yLim+d, yLim
+			}
+			if fxyBest < x+y {
+				fxyBest, fxBest = x+y, x
+			}
+		}
+		bxyBest, bxBest := maxInt, 0
+		for d := bMax; d >= bMin; d -= 2 {
+			x := bd[o+d]
+			if x < xOff {
+				x = xOff
+			}
+			y := x - d
+			if y < yOff {
+				x, y = yOff+d, yOff
+			}
+			if x+y < bxyBest {
+				bxyBest, bxBest = x+y, x
+			}
+		}
+		if (xLim+yLim)-bxyBest < fxyBest-(xOff+yOff) {
+			return fxBest, fxyBest - fxBest, true, false
+		}
+		return bxBest, bxyBest - bxBest, false, true
+	}
+}
+
+// shiftBoundaries slides each run of changes in lines up to merge with the
+// runs before it, then down to merge with the runs after it, and finally
+// back up to line up with a run of changes in the other input if it can.
+// changed and otherChanged are offset by one, as in differ.
+func (g *differ) shiftBoundaries(lines []int, changed, otherChanged []bool) {
+	equal := func(i, j int) bool {
+		return lines[i-1] == lines[j-1]
+	}
+	i, j := 1, 1
+	iEnd := len(lines) + 1
+	for {
+		// The start of the next run of changes, and the corresponding point
+		// in the other input
+		for i < iEnd && !changed[i] {
+			for otherChanged[j] {
+				j++
+			}
+			j++
+			i++
+		}
+		if i == iEnd {
+			break
+		}
+		start := i
+
+		// The end of the run
+		for i++; changed[i]; i++ {
+		}
+		for otherChanged[j] {
+			j++
+		}
+
+		var corresponding int
+		for {
+			runLength := i - start
+
+			// Move the run up while the line before it matches its last
+			// line, merging with earlier runs
+			for start > 1 && equal(start-1, i-1) {
+				start--
+				changed[start] = true
+				i--
+				changed[i] = false
+				for changed[start-1] {
+					start--
+				}
+				for j--; otherChanged[j]; j-- {
+				}
+			}
+
+			// Where the run last lines up with a run in the other input, or
+			// iEnd if it doesn't
+			corresponding = iEnd
+			if otherChanged[j-1] {
+				corresponding = i
+			}
+
+			// Then down while its first line matches the line after it,
+			// merging with later runs
+			for i != iEnd && equal(start, i) {
+				changed[start] = false
+				start++
+				changed[i] = true
+				i++
+				for changed[i] {
+					i++
+				}
+				for j++; otherChanged[j]; j++ {
+					corresponding = i
+				}
+			}
+
+			if runLength == i-start {
+				break
+			}
+		}
+
+		// Move the merged run back up to line up with a run in the other
+		// input, if there is one
+		for corresponding < i {
+			start--
+			changed[start] = true
+			i--
+			changed[i] = false
+			for j--; otherChanged[j]; j-- {
+			}
+		}
+	}
+}
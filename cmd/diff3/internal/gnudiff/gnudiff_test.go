@@ -0,0 +1,89 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package gnudiff
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/wk-y/diff"
+)
+
+// changes lists the removed and added lines of a diff by their line numbers,
+// like "-1+2"
+func changes(d []diff.DiffPart) string {
+	var sb strings.Builder
+	var i, j int
+	for _, part := range d {
+		switch part.Action {
+		case diff.DiffIdentical:
+			i++
+			j++
+		case diff.DiffRemoved:
+			i++
+			fmt.Fprintf(&sb, "-%v", i)
+		case diff.DiffAdded:
+			j++
+			fmt.Fprintf(&sb, "+%v", j)
+		}
+	}
+	return sb.String()
+}
+
+// Each letter is a line. The expected changes are those of GNU diff with
+// --horizon-lines=100, like diff3 runs it, which differ from those of
+// diff.Diff.
+func TestDiff(t *testing.T) {
+	for _, testCase := range []struct {
+		a, b     string
+		expected string
+	}{
+		{"acbccb", "caacacbca", "-1-3+2+3+5+8+9"},
+		{"baabcba", "abaabbba", "+1-5+6"},
+		{"cca", "ccbcbcaab", "+3+4+5+6+7+9"},
+		{"bcccabb", "cbcbabc", "-1-2+2-5+5+7"},
+		{"abcccaac", "bbcbccbc", "-1+1+4-6-7+7"},
+		{"acacabbcbb", "ababbcbb", "-2-3-4+2"},
+		{"acbb", "baabcc", "-1-2+2+3+5+6"},
+		{"abaaabccb", "acbccc", "-2-3-4-5+2-9+6"},
+	} {
+		a, b := strings.Split(testCase.a, ""), strings.Split(testCase.b, "")
+		if result := changes(Diff(a, b)); result != testCase.expected {
+			t.Errorf("%v to %v: Expected %v, got %v", testCase.a, testCase.b, testCase.expected, result)
+		}
+	}
+}
+
+// GNU diff gives up on a minimal diff for lines with many matches, but the
+// diff must still turn a into b
+func TestDiffValid(t *testing.T) {
+	r := rand.New(rand.NewSource(15))
+	for iter := 0; iter < 500; iter++ {
+		a := make([]string, r.Intn(40))
+		for i := range a {
+			a[i] = fmt.Sprint(r.Intn(6))
+		}
+		b := make([]string, r.Intn(40))
+		for i := range b {
+			b[i] = fmt.Sprint(r.Intn(6))
+		}
+
+		d := Diff(a, b)
+		var resultA, resultB []string
+		for _, part := range d {
+			if part.Action != diff.DiffAdded {
+				resultA = append(resultA, part.Value)
+			}
+			if part.Action != diff.DiffRemoved {
+				resultB = append(resultB, part.Value)
+			}
+		}
+		if strings.Join(resultA, " ") != strings.Join(a, " ") || strings.Join(resultB, " ") != strings.Join(b, " ") {
+			t.Fatalf("%v does not turn %v into %v", d, a, b)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Compares three files line-by-line, and shows or merges the changes like
+// GNU diff3
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/wk-y/diff/cmd/diff3/internal/gnudiff"
+	"github.com/wk-y/diff/internal/exitcodes"
+	"github.com/wk-y/diff/internal/strutils"
+	"github.com/wk-y/diff/merge"
+)
+
+var merged bool
+var ed, showOverlap, easyOnly, overlapOnly, showAll bool
+var saveQuit bool
+var labels labelsFlag
+
+func init() {
+	for _, name := range []string{"m", "merge"} {
+		flag.BoolVar(&merged, name, false, "Output the merged file instead of an ed script (implies -A)")
+	}
+	for _, name := range []string{"e", "ed"} {
+		flag.BoolVar(&ed, name, false, "Output unmerged changes from OLDFILE to YOURFILE into MYFILE")
+	}
+	for _, name := range []string{"E", "show-overlap"} {
+		flag.BoolVar(&showOverlap, name, false, "Like -e, but bracket conflicts")
+	}
+	for _, name := range []string{"3", "easy-only"} {
+		flag.BoolVar(&easyOnly, name, false, "Like -e, but incorporate only nonoverlapping changes")
+	}
+	for _, name := range []string{"x", "overlap-only"} {
+		flag.BoolVar(&overlapOnly, name, false, "Like -e, but incorporate only overlapping changes")
+	}
+	for _, name := range []string{"A", "show-all"} {
+		flag.BoolVar(&showAll, name, false, "Output all changes, bracketing conflicts")
+	}
+	flag.BoolVar(&saveQuit, "i", false, "Append 'w' and 'q' commands to ed scripts")
+	for _, name := range []string{"L", "label"} {
+		flag.Var(&labels, name, "Use `LABEL` instead of the file name, can be given up to three times")
+	}
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %v [OPTION]... MYFILE OLDFILE YOURFILE\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+}
+
+// labelsFlag collects the -L labels in order.
+type labelsFlag []string
+
+func (l *labelsFlag) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ", ")
+}
+
+func (l *labelsFlag) Set(value string) error {
+	if len(*l) == 3 {
+		return fmt.Errorf("too many file label options")
+	}
+	*l = append(*l, value)
+	return nil
+}
+
+// How conflicts are handled when merging
+type conflictMode int
+
+const (
+	conflictsKept      conflictMode = iota // Leave MYFILE's version
+	conflictsReplaced                      // Use YOURFILE's version
+	conflictsBracketed                     // Show both with markers
+)
+
+// mergeMode describes which changes are incorporated into MYFILE, either by
+// an ed script or with -m.
+type mergeMode struct {
+	theirs    bool // Incorporate changes only made by YOURFILE
+	conflicts conflictMode
+	// Bracket conflicts with the OLDFILE lines as well, and bracket changes
+	// made by both sides, like -A
+	showAll bool
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 3 {
+		flag.Usage()
+		os.Exit(exitcodes.Trouble)
+	}
+
+	mode, ok := selectMode()
+	if !ok {
+		fmt.Fprintln(os.Stderr, "diff3: incompatible options")
+		os.Exit(exitcodes.Trouble)
+	}
+
+	var files [3][]string
+	for i := range files {
+		var err error
+		files[i], err = readLines(flag.Arg(i))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "diff3: %v\n", err)
+			os.Exit(exitcodes.Trouble)
+		}
+	}
+	names := [3]string{flag.Arg(0), flag.Arg(1), flag.Arg(2)}
+	copy(names[:], labels)
+
+	format := formatNormal
+	switch {
+	case merged:
+		format = formatMerged
+	case ed || showOverlap || easyOnly || overlapOnly || showAll:
+		format = formatEd
+	}
+	output, conflicts := compare(files, names, format, mode)
+	if format == formatEd && saveQuit {
+		output += "w\nq\n"
+	}
+
+	fmt.Print(output)
+	if conflicts {
+		os.Exit(exitcodes.Conflicts)
+	}
+	os.Exit(exitcodes.NoConflicts)
+}
+
+// The kinds of output diff3 can make
+type outputFormat int
+
+const (
+	formatNormal outputFormat = iota // The changed regions of each file
+	formatEd                         // An ed script, with -e and the like
+	formatMerged                     // The merged file, with -m
+)
+
+// compare returns the output of diff3 for MYFILE, OLDFILE and YOURFILE, and
+// whether any conflicts were bracketed.
+func compare(files [3][]string, names [3]string, format outputFormat, mode mergeMode) (string, bool) {
+	mine, older, yours := files[0], files[1], files[2]
+
+	// Lines are matched up like GNU diff3 does, with GNU diff's algorithm
+	switch format {
+	case formatMerged:
+		return mergedString(merge.MergeFunc(older, mine, yours, gnudiff.Diff), mode, names)
+	case formatEd:
+		return edScript(merge.MergeFunc(older, mine, yours, gnudiff.Diff), mode, names)
+	}
+	// Like GNU diff3, compare the files against YOURFILE rather than OLDFILE
+	// when not merging, which can line them up differently
+	return normalString(merge.MergeFunc(yours, mine, older, gnudiff.Diff)), false
+}
+
+// selectMode returns the merge mode selected by the flags, or false if the
+// flags can't be used together.
+func selectMode() (mergeMode, bool) {
+	selected := 0
+	for _, enabled := range []bool{ed, showOverlap, easyOnly, overlapOnly, showAll} {
+		if enabled {
+			selected++
+		}
+	}
+	if selected > 1 {
+		return mergeMode{}, false
+	}
+
+	var mode mergeMode
+	switch {
+	case ed:
+		mode = mergeMode{theirs: true, conflicts: conflictsReplaced}
+	case showOverlap:
+		mode = mergeMode{theirs: true, conflicts: conflictsBracketed}
+	case easyOnly:
+		mode = mergeMode{theirs: true, conflicts: conflictsKept}
+	case overlapOnly:
+		mode = mergeMode{conflicts: conflictsReplaced}
+	default:
+		// -A, which is also the default for -m
+		mode = mergeMode{theirs: true, conflicts: conflictsBracketed, showAll: true}
+	}
+
+	// Like GNU diff3, labels are only accepted when there are conflict
+	// markers to put them on
+	normal := !merged && selected == 0
+	if len(labels) > 0 && (normal || mode.conflicts != conflictsBracketed) {
+		return mergeMode{}, false
+	}
+	return mode, true
+}
+
+func readLines(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return strutils.ReadLines(f)
+}
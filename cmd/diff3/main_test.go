@@ -0,0 +1,121 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wk-y/diff/internal/exitcodes"
+	"github.com/wk-y/diff/internal/strutils"
+)
+
+// Each directory in testdata/gnu has a MYFILE, OLDFILE and YOURFILE, with
+// the output and exit status of GNU diff3 for each of its modes
+func TestCompareGNU(t *testing.T) {
+	modes := []struct {
+		name   string
+		format outputFormat
+		mode   mergeMode
+	}{
+		{"normal", formatNormal, mergeMode{}},
+		{"ed", formatEd, mergeMode{theirs: true, conflicts: conflictsReplaced}},
+		{"show-overlap", formatEd, mergeMode{theirs: true, conflicts: conflictsBracketed}},
+		{"easy-only", formatEd, mergeMode{theirs: true, conflicts: conflictsKept}},
+		{"overlap-only", formatEd, mergeMode{conflicts: conflictsReplaced}},
+		{"show-all", formatEd, mergeMode{theirs: true, conflicts: conflictsBracketed, showAll: true}},
+		{"merge", formatMerged, mergeMode{theirs: true, conflicts: conflictsBracketed, showAll: true}},
+	}
+
+	dirs, err := filepath.Glob(filepath.Join("testdata", "gnu", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) == 0 {
+		t.Fatal("No test cases found")
+	}
+
+	for _, dir := range dirs {
+		names := [3]string{"mine", "older", "yours"}
+		var files [3][]string
+		for i, name := range names {
+			if files[i], err = readLines(filepath.Join(dir, name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		status, err := os.ReadFile(filepath.Join(dir, "status"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The exit status of each mode, one "name status" pair per line
+		statuses := map[string]int{}
+		for _, line := range strutils.SplitLines(string(status)) {
+			var name string
+			var code int
+			if _, err := fmt.Sscan(line, &name, &code); err != nil {
+				t.Fatal(err)
+			}
+			statuses[name] = code
+		}
+
+		for _, testCase := range modes {
+			expected, err := os.ReadFile(filepath.Join(dir, testCase.name+".out"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			output, conflicts := compare(files, names, testCase.format, testCase.mode)
+			if output != string(expected) {
+				t.Errorf("%v %v: Expected %q, got %q", dir, testCase.name, expected, output)
+			}
+
+			code := exitcodes.NoConflicts
+			if conflicts {
+				code = exitcodes.Conflicts
+			}
+			if expected := statuses[testCase.name]; code != expected {
+				t.Errorf("%v %v: Expected status %v, got %v", dir, testCase.name, expected, code)
+			}
+		}
+	}
+}
+
+// Like GNU diff3, -L is only accepted when conflicts are bracketed
+func TestSelectModeLabels(t *testing.T) {
+	defer func() {
+		merged, ed, showOverlap, easyOnly, overlapOnly, showAll = false, false, false, false, false, false
+		labels = nil
+	}()
+	for _, testCase := range []struct {
+		name     string
+		flags    []*bool
+		expected bool
+	}{
+		{"normal", nil, false},
+		{"-e", []*bool{&ed}, false},
+		{"-E", []*bool{&showOverlap}, true},
+		{"-3", []*bool{&easyOnly}, false},
+		{"-x", []*bool{&overlapOnly}, false},
+		{"-A", []*bool{&showAll}, true},
+		{"-m", []*bool{&merged}, true},
+		{"-m -e", []*bool{&merged, &ed}, false},
+		{"-m -E", []*bool{&merged, &showOverlap}, true},
+	} {
+		merged, ed, showOverlap, easyOnly, overlapOnly, showAll = false, false, false, false, false, false
+		for _, flag := range testCase.flags {
+			*flag = true
+		}
+
+		labels = nil
+		if _, ok := selectMode(); !ok {
+			t.Errorf("%v: Expected the flags to be accepted without -L", testCase.name)
+		}
+		labels = labelsFlag{"mine"}
+		if _, ok := selectMode(); ok != testCase.expected {
+			t.Errorf("%v -L: Expected %v, got %v", testCase.name, testCase.expected, ok)
+		}
+	}
+}
@@ -5,16 +5,30 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"strings"
 
+	"github.com/wk-y/diff/bindiff"
 	"github.com/wk-y/diff/internal/exitcodes"
 	"github.com/wk-y/diff/internal/strutils"
+	"github.com/wk-y/diff/jsondiff"
 	"github.com/wk-y/diff/patching"
 )
 
+var jsonMergePatch bool
+var vcdiff bool
+var reverse bool
+
+func init() {
+	flag.BoolVar(&reverse, "R", false, "Undo the patch, assuming it was made with the files swapped or was already applied")
+	flag.BoolVar(&vcdiff, "vcdiff", false, "The patch is an RFC 3284 VCDIFF delta, as written by diff -vcdiff")
+	flag.BoolVar(&jsonMergePatch, "json-merge-patch", false, "The patch is an RFC 7386 JSON Merge Patch to apply to a JSON file")
+}
+
 func main() {
 	flag.Parse()
 
@@ -23,6 +37,11 @@ func main() {
 		return
 	}
 
+	if reverse && (vcdiff || jsonMergePatch) {
+		fmt.Println("-R can't be used with -vcdiff or -json-merge-patch")
+		os.Exit(1)
+	}
+
 	originalFileName := flag.Arg(0)
 	patchFileName := flag.Arg(1)
 
@@ -38,6 +57,30 @@ func main() {
 		os.Exit(exitcodes.IoError)
 	}
 
+	gitBinary := strings.HasPrefix(string(patchBytes), "GIT binary patch\n") ||
+		strings.Contains(string(patchBytes), "\nGIT binary patch\n")
+	if jsonMergePatch || vcdiff || gitBinary {
+		var result []byte
+		switch {
+		case vcdiff:
+			result, err = bindiff.Apply(originalBytes, patchBytes)
+		case jsonMergePatch:
+			result, err = applyJSONMergePatch(originalBytes, patchBytes)
+		default:
+			result, err = applyBinaryPatch(originalBytes, patchBytes)
+		}
+		if err != nil {
+			fmt.Printf("Failed to apply patch: %v\n", err)
+			os.Exit(1)
+		}
+		err = os.WriteFile(originalFileName, result, 0o664)
+		if err != nil {
+			fmt.Printf("Error writing file: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Skip the first two lines of the diff
 	// TODO: Actually parse the diff header
 	patchLines := strutils.SplitLines(string(patchBytes))
@@ -61,8 +104,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	if reverse {
+		hunks = patching.ReverseHunks(hunks)
+	}
 	b, err := patching.ApplyHunks(a, hunks)
 	if err != nil {
+		// Like GNU patch, check whether the patch goes the other way
+		if _, reverseErr := patching.ApplyHunks(a, patching.ReverseHunks(hunks)); reverseErr == nil {
+			fmt.Println(reversedMessage())
+		}
 		fmt.Printf("Failed to apply patch: %v\n", err)
 		os.Exit(1)
 	}
@@ -73,3 +123,64 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// applyJSONMergePatch applies a JSON Merge Patch to a JSON document, and
+// returns the result indented with two spaces.
+func applyJSONMergePatch(original, patch []byte) ([]byte, error) {
+	var doc, mergePatch interface{}
+	for _, input := range []struct {
+		name  string
+		data  []byte
+		value *interface{}
+	}{{"original file", original, &doc}, {"patch", patch, &mergePatch}} {
+		decoder := json.NewDecoder(bytes.NewReader(input.data))
+		// Keep numbers as written, so that large integers aren't rounded
+		decoder.UseNumber()
+		if err := decoder.Decode(input.value); err != nil {
+			return nil, fmt.Errorf("invalid JSON in %v: %w", input.name, err)
+		}
+	}
+
+	var result bytes.Buffer
+	encoder := json.NewEncoder(&result)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(jsondiff.ApplyMergePatch(doc, mergePatch)); err != nil {
+		return nil, err
+	}
+	return result.Bytes(), nil
+}
+
+// applyBinaryPatch applies a binary patch made by git diff --binary, or its
+// reverse section if -R was given.
+func applyBinaryPatch(original, patch []byte) ([]byte, error) {
+	p, err := patching.ParseBinaryPatch(string(patch))
+	if err != nil {
+		return nil, err
+	}
+	if reverse {
+		if p, err = p.Reversed(); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := patching.ApplyBinaryPatch(original, p)
+	// Without hashes, only a delta can tell whether it was made for the file,
+	// since a literal applies to anything
+	if err != nil && p.Reverse != nil && (p.NewHash != "" || p.Reverse.Kind == patching.BinaryDelta) {
+		undo, _ := p.Reversed()
+		if _, reverseErr := patching.ApplyBinaryPatch(original, undo); reverseErr == nil {
+			return nil, fmt.Errorf("%v: %w", reversedMessage(), err)
+		}
+	}
+	return result, err
+}
+
+// reversedMessage returns what GNU patch says when a patch doesn't apply,
+// but does in the other direction.
+func reversedMessage() string {
+	if reverse {
+		return "Unreversed patch detected! Try without -R."
+	}
+	return "Reversed (or previously applied) patch detected! Use -R to undo it."
+}
@@ -0,0 +1,75 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/wk-y/diff/patching"
+)
+
+func TestApplyBinaryPatchReversed(t *testing.T) {
+	defer func(r bool) { reverse = r }(reverse)
+
+	a := bytes.Repeat([]byte("\x00binary\x01"), 1000)
+	b := append(append([]byte{}, a...), "\x00more"...)
+	p := patching.BinaryDiff(a, b)
+	patch := []byte("index " + p.OldHash + ".." + p.NewHash + " 100644\n" + p.String())
+
+	testCases := []struct {
+		reverse  bool
+		original []byte
+		expected []byte
+		message  string
+	}{
+		{false, a, b, ""},
+		{false, b, nil, "Reversed (or previously applied) patch detected!"},
+		{true, b, a, ""},
+		{true, a, nil, "Unreversed patch detected!"},
+		{false, []byte("\x00other"), nil, "the file has hash"},
+	}
+	for _, testCase := range testCases {
+		reverse = testCase.reverse
+		result, err := applyBinaryPatch(testCase.original, patch)
+		if testCase.message != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), testCase.message) {
+				t.Errorf("Expected an error starting with %q, got %v", testCase.message, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		} else if !bytes.Equal(result, testCase.expected) {
+			t.Errorf("Expected %v bytes, got %v", len(testCase.expected), len(result))
+		}
+	}
+}
+
+// A literal applies to any file, so only the hashes stop it replacing the
+// wrong one
+func TestApplyBinaryPatchLiteral(t *testing.T) {
+	defer func(r bool) { reverse = r }(reverse)
+	reverse = false
+
+	a, b := []byte("\x00old"), []byte("\x00new")
+	p := patching.BinaryDiff(a, b)
+	if p.Forward.Kind != patching.BinaryLiteral {
+		t.Fatalf("Expected a literal, got %v", p.Forward.Kind)
+	}
+	patch := []byte("index " + p.OldHash + ".." + p.NewHash + " 100644\n" + p.String())
+
+	if result, err := applyBinaryPatch(a, patch); err != nil || !bytes.Equal(result, b) {
+		t.Errorf("Expected %q, got %q, %v", b, result, err)
+	}
+	expected := "Reversed (or previously applied) patch detected!"
+	if _, err := applyBinaryPatch(b, patch); err == nil || !strings.HasPrefix(err.Error(), expected) {
+		t.Errorf("Expected an error starting with %q, got %v", expected, err)
+	}
+	if _, err := applyBinaryPatch([]byte("\x00other"), patch); err == nil {
+		t.Error("Expected an error applying to another file")
+	}
+}
@@ -0,0 +1,394 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+// The constants of git's indent heuristic (xdiff/xdiffi.c), which were tuned
+// by hand against a corpus of real diffs.
+const (
+	indentMaxBlanks  = 20  // Blank lines to look through for an indent
+	indentMax        = 200 // Indents deeper than this are all the same
+	indentMaxSliding = 100 // Positions to try when sliding a group
+
+	startOfFilePenalty              = 1
+	endOfFilePenalty                = 21
+	totalBlankWeight                = -30
+	postBlankWeight                 = 6
+	relativeIndentPenalty           = -4
+	relativeIndentWithBlankPenalty  = 10
+	relativeOutdentPenalty          = 24
+	relativeOutdentWithBlankPenalty = 17
+	relativeDedentPenalty           = 23
+	relativeDedentWithBlankPenalty  = 17
+	indentWeight                    = 60
+)
+
+// Compact returns a copy of d where each group of added or removed lines
+// that could equally well be placed higher or lower is slid to the position
+// that reads best, using git's indent heuristic. For example, an added
+// function is shown ending with its own closing brace rather than starting
+// with the closing brace of the function before it.
+//
+// Like git, the removed and the added lines are slid separately, each over
+// the lines of its own side, so the two halves of a replacement can slide
+// apart. A group that can be lined up with a change on the other side is
+// slid there instead, so that the two are shown together. Groups that
+// contain moved lines are left alone.
+//
+// Only the positions of the changes move, so the result is still a diff of
+// the same two inputs with the same number of changes. Between two identical
+// lines the removed lines come before the added lines.
+func Compact(d []DiffPart) []DiffPart {
+	var a, b compactSide
+	for _, part := range d {
+		switch part.Action {
+		case DiffIdentical:
+			a.add(part, false)
+			b.add(part, false)
+		case DiffRemoved, DiffMovedFrom:
+			a.add(part, true)
+		case DiffAdded, DiffMovedTo:
+			b.add(part, true)
+		}
+	}
+	aMoved := a.compact(&b)
+	bMoved := b.compact(&a)
+	if !aMoved && !bMoved {
+		result := make([]DiffPart, len(d))
+		copy(result, d)
+		return result
+	}
+
+	result := make([]DiffPart, 0, len(d))
+	var i, j int
+	for i < len(a.parts) || j < len(b.parts) {
+		switch {
+		case i < len(a.parts) && a.changed[i]:
+			result = append(result, a.parts[i])
+			i++
+		case j < len(b.parts) && b.changed[j]:
+			result = append(result, b.parts[j])
+			j++
+		default:
+			// Both sides have the same number of unchanged lines, so these
+			// are the next pair
+			result = append(result, DiffPart{DiffIdentical, a.parts[i].Value})
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// compactSide holds the lines of one side of a diff while its groups of
+// changes are slid.
+type compactSide struct {
+	parts   []DiffPart // The changes of this side, and the identical lines
+	lines   []string
+	changed []bool
+}
+
+func (s *compactSide) add(part DiffPart, changed bool) {
+	s.parts = append(s.parts, part)
+	s.lines = append(s.lines, part.Value)
+	s.changed = append(s.changed, changed)
+}
+
+// hasMoved reports whether any of the lines in [start, end) were moved.
+func (s *compactSide) hasMoved(start, end int) bool {
+	for _, part := range s.parts[start:end] {
+		if part.Action == DiffMovedFrom || part.Action == DiffMovedTo {
+			return true
+		}
+	}
+	return false
+}
+
+// compact slides each group of changes of s to its best position, like
+// xdl_change_compact in git's xdiff/xdiffi.c, and reports whether any group
+// moved. other is the other side of the diff, which doesn't change.
+func (s *compactSide) compact(other *compactSide) bool {
+	// Where the unchanged lines of the other side are, so that the group of
+	// changes of the other side that lines up with a group of s can be found
+	var otherUnchanged []int
+	for i, changed := range other.changed {
+		if !changed {
+			otherUnchanged = append(otherUnchanged, i)
+		}
+	}
+	// otherChanged reports whether the other side has changes between its
+	// unchanged lines k-1 and k
+	otherChanged := func(k int) bool {
+		start, end := 0, len(other.changed)
+		if k > 0 {
+			start = otherUnchanged[k-1] + 1
+		}
+		if k < len(otherUnchanged) {
+			end = otherUnchanged[k]
+		}
+		return start < end
+	}
+
+	moved := false
+	n := len(s.lines)
+	var start, end int
+	k := 0 // The unchanged lines before start
+	// extend grows the group over the changes next to it
+	extend := func() {
+		start, end = s.groupStart(start), s.groupEnd(end)
+	}
+	// The group can slide by one if the line it passes over is equal to the
+	// line at its other end, which only swaps whether the two are changed.
+	// It can't slide into a group with moved lines.
+	slideUp := func() bool {
+		if start == 0 || s.lines[start-1] != s.lines[end-1] ||
+			(start > 1 && s.changed[start-2] && s.hasMoved(s.groupStart(start-2), start-1)) {
+			return false
+		}
+		start--
+		end--
+		s.swap(start, end)
+		k--
+		moved = true
+		extend()
+		return true
+	}
+	slideDown := func() bool {
+		if end == n || s.lines[start] != s.lines[end] ||
+			(end+1 < n && s.changed[end+1] && s.hasMoved(end+1, s.groupEnd(end+1))) {
+			return false
+		}
+		s.swap(start, end)
+		start++
+		end++
+		k++
+		moved = true
+		extend()
+		return true
+	}
+
+	for start < n {
+		if !s.changed[start] {
+			start++
+			k++
+			continue
+		}
+		end = start
+		extend()
+		if s.hasMoved(start, end) {
+			start = end
+			continue
+		}
+
+		var earliestEnd int
+		endMatchingOther := -1
+		for {
+			size := end - start
+			// Slide as far up as possible, then note how far down it can go,
+			// and where along the way it lines up with changes on the other
+			// side. Sliding can merge it with the groups next to it, in which
+			// case the bigger group is slid again.
+			for slideUp() {
+			}
+			earliestEnd = end
+			if otherChanged(k) {
+				endMatchingOther = end
+			}
+			for slideDown() {
+				if otherChanged(k) {
+					endMatchingOther = end
+				}
+			}
+			if end-start == size {
+				break
+			}
+		}
+
+		switch {
+		case end == earliestEnd:
+		case endMatchingOther != -1:
+			for !otherChanged(k) && slideUp() {
+			}
+		default:
+			// Score the splits before and after the group at each position,
+			// like git, trying the positions from the top
+			size := end - start
+			lowest := earliestEnd
+			if end-size-1 > lowest {
+				lowest = end - size - 1
+			}
+			if end-indentMaxSliding > lowest {
+				lowest = end - indentMaxSliding
+			}
+			best := -1
+			var bestScore splitScore
+			for shift := lowest; shift <= end; shift++ {
+				score := splitScore{}
+				score.add(measureSplit(s.lines, shift))
+				score.add(measureSplit(s.lines, shift-size))
+				if best == -1 || score.compare(bestScore) <= 0 {
+					best = shift
+					bestScore = score
+				}
+			}
+			for end > best && slideUp() {
+			}
+		}
+		start = end
+	}
+	return moved
+}
+
+// groupStart returns the start of the group of changes that includes line i.
+func (s *compactSide) groupStart(i int) int {
+	for i > 0 && s.changed[i-1] {
+		i--
+	}
+	return i
+}
+
+// groupEnd returns the end of the group of changes that includes line i.
+func (s *compactSide) groupEnd(i int) int {
+	for i < len(s.changed) && s.changed[i] {
+		i++
+	}
+	return i
+}
+
+// swap swaps whether two equal lines are changed. Since the values are
+// equal, only the actions need to be swapped.
+func (s *compactSide) swap(i, j int) {
+	s.changed[i], s.changed[j] = s.changed[j], s.changed[i]
+	s.parts[i].Action, s.parts[j].Action = s.parts[j].Action, s.parts[i].Action
+}
+
+// indent returns the width of the leading whitespace of line, counting tabs
+// to the next multiple of 8, or -1 if the line is blank.
+func indent(line string) int {
+	width := 0
+	for _, c := range []byte(line) {
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += 8 - width%8
+		case '\n', '\v', '\f', '\r':
+		default:
+			return width
+		}
+		if width >= indentMax {
+			return indentMax
+		}
+	}
+	return -1
+}
+
+// splitMeasurement describes the lines around a split between lines[split-1]
+// and lines[split].
+type splitMeasurement struct {
+	endOfFile  bool
+	indent     int // Indent of the line after the split, or -1 if blank
+	preBlank   int // Blank lines before the split
+	preIndent  int // Indent of the nearest non-blank line before the split
+	postBlank  int // Blank lines after the line after the split
+	postIndent int // Indent of the nearest non-blank line after that
+}
+
+func measureSplit(lines []string, split int) splitMeasurement {
+	m := splitMeasurement{indent: -1, preIndent: -1, postIndent: -1}
+	if split >= len(lines) {
+		m.endOfFile = true
+	} else {
+		m.indent = indent(lines[split])
+	}
+
+	for i := split - 1; i >= 0; i-- {
+		m.preIndent = indent(lines[i])
+		if m.preIndent != -1 {
+			break
+		}
+		m.preBlank++
+		if m.preBlank == indentMaxBlanks {
+			m.preIndent = 0
+			break
+		}
+	}
+
+	for i := split + 1; i < len(lines); i++ {
+		m.postIndent = indent(lines[i])
+		if m.postIndent != -1 {
+			break
+		}
+		m.postBlank++
+		if m.postBlank == indentMaxBlanks {
+			m.postIndent = 0
+			break
+		}
+	}
+
+	return m
+}
+
+// splitScore rates how good a position is for a group of changes. Lower is
+// better.
+type splitScore struct {
+	effectiveIndent int
+	penalty         int
+}
+
+func (s *splitScore) add(m splitMeasurement) {
+	if m.preIndent == -1 && m.preBlank == 0 {
+		s.penalty += startOfFilePenalty
+	}
+	if m.endOfFile {
+		s.penalty += endOfFilePenalty
+	}
+
+	postBlank := 0
+	if m.indent == -1 {
+		postBlank = 1 + m.postBlank
+	}
+	totalBlank := m.preBlank + postBlank
+	anyBlanks := totalBlank != 0
+	s.penalty += totalBlankWeight * totalBlank
+	s.penalty += postBlankWeight * postBlank
+
+	indent := m.indent
+	if indent == -1 {
+		indent = m.postIndent
+	}
+	s.effectiveIndent += indent
+
+	switch {
+	case indent == -1 || m.preIndent == -1 || indent == m.preIndent:
+	case indent > m.preIndent:
+		if anyBlanks {
+			s.penalty += relativeIndentWithBlankPenalty
+		} else {
+			s.penalty += relativeIndentPenalty
+		}
+	case m.postIndent != -1 && m.postIndent > indent:
+		if anyBlanks {
+			s.penalty += relativeOutdentWithBlankPenalty
+		} else {
+			s.penalty += relativeOutdentPenalty
+		}
+	default:
+		if anyBlanks {
+			s.penalty += relativeDedentWithBlankPenalty
+		} else {
+			s.penalty += relativeDedentPenalty
+		}
+	}
+}
+
+func (s splitScore) compare(other splitScore) int {
+	cmp := 0
+	if s.effectiveIndent > other.effectiveIndent {
+		cmp = 1
+	} else if s.effectiveIndent < other.effectiveIndent {
+		cmp = -1
+	}
+	return indentWeight*cmp + s.penalty - other.penalty
+}
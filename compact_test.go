@@ -0,0 +1,128 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	d := []DiffPart{
+		{DiffIdentical, "func a() {"},
+		{DiffIdentical, "\treturn"},
+		{DiffAdded, "}"},
+		{DiffAdded, ""},
+		{DiffAdded, "func b() {"},
+		{DiffAdded, "\treturn"},
+		{DiffIdentical, "}"},
+		{DiffIdentical, ""},
+		{DiffIdentical, "func c() {"},
+	}
+	expected := []DiffPart{
+		{DiffIdentical, "func a() {"},
+		{DiffIdentical, "\treturn"},
+		{DiffIdentical, "}"},
+		{DiffIdentical, ""},
+		{DiffAdded, "func b() {"},
+		{DiffAdded, "\treturn"},
+		{DiffAdded, "}"},
+		{DiffAdded, ""},
+		{DiffIdentical, "func c() {"},
+	}
+	if result := Compact(d); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+	if d[2].Action != DiffAdded {
+		t.Errorf("Expected Compact not to modify its input")
+	}
+
+	// A removed block inside a nested scope stays inside the scope
+	d = []DiffPart{
+		{DiffIdentical, "if x {"},
+		{DiffIdentical, "\tif y {"},
+		{DiffIdentical, "\t\tf()"},
+		{DiffRemoved, "\t}"},
+		{DiffRemoved, "\tif z {"},
+		{DiffRemoved, "\t\tf()"},
+		{DiffIdentical, "\t}"},
+		{DiffIdentical, "}"},
+	}
+	expected = []DiffPart{
+		{DiffIdentical, "if x {"},
+		{DiffIdentical, "\tif y {"},
+		{DiffIdentical, "\t\tf()"},
+		{DiffIdentical, "\t}"},
+		{DiffRemoved, "\tif z {"},
+		{DiffRemoved, "\t\tf()"},
+		{DiffRemoved, "\t}"},
+		{DiffIdentical, "}"},
+	}
+	if result := Compact(d); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	// The removed half of a replacement slides on its own, to line up with
+	// the other added line
+	d = []DiffPart{
+		{DiffRemoved, "a"},
+		{DiffAdded, "b"},
+		{DiffIdentical, "a"},
+		{DiffAdded, "d"},
+		{DiffIdentical, "c"},
+	}
+	expected = []DiffPart{
+		{DiffAdded, "b"},
+		{DiffIdentical, "a"},
+		{DiffRemoved, "a"},
+		{DiffAdded, "d"},
+		{DiffIdentical, "c"},
+	}
+	if result := Compact(d); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	// Moved lines stay where they are
+	d = []DiffPart{
+		{DiffMovedFrom, "a"},
+		{DiffAdded, "b"},
+		{DiffIdentical, "a"},
+		{DiffAdded, "d"},
+		{DiffIdentical, "c"},
+		{DiffMovedTo, "a"},
+	}
+	if result := Compact(d); !reflect.DeepEqual(result, d) {
+		t.Errorf("Expected %v, got %v", d, result)
+	}
+}
+
+func TestCompactValid(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	for i := 0; i < 200; i++ {
+		a := randomLines(rng, rng.Intn(30), 3)
+		b := randomLines(rng, rng.Intn(30), 3)
+		d := Diff(a, b)
+		result := Compact(d)
+
+		resultA, resultB := extractOriginals(result)
+		if !equalLines(resultA, a) || !equalLines(resultB, b) {
+			t.Fatalf("Compact(Diff(%q, %q)) = %v is not a diff of its inputs", a, b, result)
+		}
+		if changes(result) != changes(d) {
+			t.Fatalf("Compact(%v) = %v changed the number of changes", d, result)
+		}
+	}
+}
+
+func changes(d []DiffPart) int {
+	count := 0
+	for _, part := range d {
+		if part.Action != DiffIdentical {
+			count++
+		}
+	}
+	return count
+}
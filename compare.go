@@ -0,0 +1,115 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// WithIgnoreCase makes Diff and LineDiff compare lines without regard to
+// case, like GNU diff's -i.
+func WithIgnoreCase() Option {
+	return withNormalization(strings.ToLower)
+}
+
+// WithIgnoreAllSpace makes Diff and LineDiff ignore all white space when
+// comparing lines, like GNU diff's -w.
+func WithIgnoreAllSpace() Option {
+	return withNormalization(func(line string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, line)
+	})
+}
+
+// WithIgnoreSpaceChange makes Diff and LineDiff ignore white space at the end
+// of lines, and treat every other run of white space as a single space, like
+// GNU diff's -b.
+func WithIgnoreSpaceChange() Option {
+	return withNormalization(func(line string) string {
+		var builder strings.Builder
+		space := false
+		for _, r := range line {
+			if unicode.IsSpace(r) {
+				space = true
+				continue
+			}
+			if space {
+				builder.WriteByte(' ')
+				space = false
+			}
+			builder.WriteRune(r)
+		}
+		return builder.String()
+	})
+}
+
+// WithIgnoreTrailingSpace makes Diff and LineDiff ignore white space at the
+// end of lines, like GNU diff's -Z.
+func WithIgnoreTrailingSpace() Option {
+	return withNormalization(func(line string) string {
+		return strings.TrimRightFunc(line, unicode.IsSpace)
+	})
+}
+
+// WithStripTrailingCR makes Diff and LineDiff ignore a carriage return at the
+// end of lines, like GNU diff's --strip-trailing-cr.
+func WithStripTrailingCR() Option {
+	return func(o *options) {
+		o.stripTrailingCR = true
+	}
+}
+
+func withNormalization(normalize func(line string) string) Option {
+	return func(o *options) {
+		o.normalize = append(o.normalize, normalize)
+	}
+}
+
+// compared returns the lines as they are compared, which is the lines
+// themselves unless an option like WithIgnoreCase is used. The line
+// terminator is kept out of the normalization, so that for example a last
+// line without a newline still differs under WithIgnoreAllSpace.
+func (o options) compared(lines []string) []string {
+	if !o.stripTrailingCR && len(o.normalize) == 0 {
+		return lines
+	}
+
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		content := strings.TrimSuffix(line, "\n")
+		terminator := line[len(content):]
+		if o.stripTrailingCR {
+			content = strings.TrimSuffix(content, "\r")
+		}
+		for _, normalize := range o.normalize {
+			content = normalize(content)
+		}
+		result[i] = content + terminator
+	}
+	return result
+}
+
+// IsBlank reports whether a line is empty apart from its terminator once it
+// is compared with opts. These are the lines GNU diff's -B ignores, so with
+// WithIgnoreAllSpace lines of only white space are blank too.
+func IsBlank(line string, opts ...Option) bool {
+	return strings.TrimSuffix(newOptions(opts).compared([]string{line})[0], "\n") == ""
+}
+
+// diffLines diffs two slices of lines, using the options that change how
+// lines are compared.
+func (o options) diffLines(a, b []string) []DiffAction {
+	a, b = o.compared(a), o.compared(b)
+	return o.diff(len(a), len(b), func(i, j int) bool {
+		return a[i] == b[j]
+	}, func() ([]int, []int) {
+		return intern(a, b)
+	})
+}
@@ -0,0 +1,80 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffComparisonOptions(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		opts     []Option
+		expected []DiffPart
+	}{
+		{
+			"Hello\nworld\n", "hello\nWORLD\n",
+			[]Option{WithIgnoreCase()},
+			[]DiffPart{{DiffIdentical, "Hello\n"}, {DiffIdentical, "world\n"}},
+		},
+		{
+			"a b\n\tc\n", "ab \nc\n",
+			[]Option{WithIgnoreAllSpace()},
+			[]DiffPart{{DiffIdentical, "a b\n"}, {DiffIdentical, "\tc\n"}},
+		},
+		{
+			"a  b \nc\n", "a\tb\n c\n",
+			[]Option{WithIgnoreSpaceChange()},
+			[]DiffPart{{DiffIdentical, "a  b \n"}, {DiffRemoved, "c\n"}, {DiffAdded, " c\n"}},
+		},
+		{
+			"a \nb\tc\n", "a\nb c\n",
+			[]Option{WithIgnoreTrailingSpace()},
+			[]DiffPart{{DiffIdentical, "a \n"}, {DiffRemoved, "b\tc\n"}, {DiffAdded, "b c\n"}},
+		},
+		{
+			"a\r\nb\r\n", "a\nB\n",
+			[]Option{WithStripTrailingCR(), WithIgnoreCase()},
+			[]DiffPart{{DiffIdentical, "a\r\n"}, {DiffIdentical, "b\r\n"}},
+		},
+		{
+			// The missing newline is still a change
+			"a\n", "a",
+			[]Option{WithIgnoreAllSpace()},
+			[]DiffPart{{DiffRemoved, "a\n"}, {DiffAdded, "a"}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		result := LineDiff(testCase.a, testCase.b, testCase.opts...)
+		if !reflect.DeepEqual(result, testCase.expected) {
+			t.Errorf("LineDiff(%q, %q): Expected %v, got %v", testCase.a, testCase.b, testCase.expected, result)
+		}
+	}
+}
+
+func TestIsBlank(t *testing.T) {
+	testCases := []struct {
+		line     string
+		opts     []Option
+		expected bool
+	}{
+		{"\n", nil, true},
+		{"", nil, true},
+		{" \t\n", nil, false},
+		{" \t\n", []Option{WithIgnoreAllSpace()}, true},
+		{" \t\n", []Option{WithIgnoreSpaceChange()}, true},
+		{"\r\n", nil, false},
+		{"\r\n", []Option{WithStripTrailingCR()}, true},
+		{"a\n", []Option{WithIgnoreAllSpace()}, false},
+	}
+
+	for _, testCase := range testCases {
+		if result := IsBlank(testCase.line, testCase.opts...); result != testCase.expected {
+			t.Errorf("IsBlank(%q): Expected %v, got %v", testCase.line, testCase.expected, result)
+		}
+	}
+}
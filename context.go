@@ -0,0 +1,136 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+)
+
+// DiffContext is like Diff, but stops early with ctx.Err() if ctx is
+// cancelled. If the deadline of ctx passes first, the rest of the diff is
+// found with a faster heuristic, like GNU diff's --speed-large-files, and
+// minimal is false. The result is still a valid diff of a and b.
+//
+// minimal is only true for AlgorithmMyers and AlgorithmLinearSpace, since the
+// other algorithms don't look for the smallest number of changes.
+func DiffContext(ctx context.Context, a, b []string, opts ...Option) (d []DiffPart, minimal bool, err error) {
+	o := newOptions(opts)
+	actions, minimal, err := o.diffContext(ctx, len(a)+len(b), func(o options) []DiffAction {
+		return o.diffLines(a, b)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return toParts(a, b, actions), minimal, nil
+}
+
+// DiffAlgorithmContext is like DiffAlgorithm, but can be cancelled or given a
+// deadline in the same way as DiffContext.
+func DiffAlgorithmContext(ctx context.Context, n, m int, equal func(i, j int) bool, opts ...Option) (d []DiffAction, minimal bool, err error) {
+	o := newOptions(opts)
+	return o.diffContext(ctx, n+m, func(o options) []DiffAction {
+		return o.diff(n, m, equal, nil)
+	})
+}
+
+// diffContext runs a diff with a budget for ctx. size is the total length of
+// the inputs.
+func (o options) diffContext(ctx context.Context, size int, run func(o options) []DiffAction) (d []DiffAction, minimal bool, err error) {
+	o.budget = &budget{
+		ctx:       ctx,
+		done:      ctx.Done(),
+		costLimit: heuristicCostLimit(size),
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			c, ok := r.(cancelled)
+			if !ok {
+				panic(r)
+			}
+			d, minimal, err = nil, false, c.err
+		}
+	}()
+
+	// Don't start at all if the context is already cancelled
+	o.budget.check()
+
+	d = run(o)
+	minimal = !o.budget.heuristic && o.weight == nil &&
+		(o.algorithm == AlgorithmMyers || o.algorithm == AlgorithmLinearSpace)
+	return d, minimal, nil
+}
+
+// budget tracks whether a diff has run out of time. A nil budget never runs
+// out. It can be shared by the goroutines of a parallel diff.
+type budget struct {
+	mu   sync.Mutex
+	ctx  context.Context
+	done <-chan struct{}
+	// Once the deadline has passed, searches give up after this many
+	// changes and settle for a non-minimal path
+	costLimit int
+	expired   bool
+	heuristic bool // Whether a non-minimal path was actually taken
+}
+
+// Panicked with to unwind a diff when its context is cancelled. It never
+// escapes the package.
+type cancelled struct {
+	err error
+}
+
+// check reports whether the deadline has passed. If the context was
+// cancelled instead, it unwinds the diff.
+func (b *budget) check() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.expired {
+		return true
+	}
+	select {
+	case <-b.done:
+		err := b.ctx.Err()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			panic(cancelled{err})
+		}
+		b.expired = true
+	default:
+	}
+	return b.expired
+}
+
+// giveUp reports whether a search that has made d changes should stop and
+// use a heuristic instead.
+func (b *budget) giveUp(d int) bool {
+	if !b.check() || d <= b.costLimit {
+		return false
+	}
+	b.useHeuristic()
+	return true
+}
+
+// useHeuristic notes that a non-minimal path was taken. It can be called from
+// the goroutines of a parallel diff.
+func (b *budget) useHeuristic() {
+	b.mu.Lock()
+	b.heuristic = true
+	b.mu.Unlock()
+}
+
+// heuristicCostLimit returns how many changes to look at before giving up,
+// which like xdiff grows with the square root of the input size.
+func heuristicCostLimit(size int) int {
+	limit := int(math.Sqrt(float64(size)))
+	if limit < 256 {
+		limit = 256
+	}
+	return limit
+}
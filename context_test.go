@@ -0,0 +1,105 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, algorithm := range []Algorithm{AlgorithmMyers, AlgorithmLinearSpace, AlgorithmPatience, AlgorithmHistogram} {
+		_, _, err := DiffContext(ctx, []string{"a"}, []string{"b"}, WithAlgorithm(algorithm))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%v: Expected context.Canceled, got %v", algorithm, err)
+		}
+	}
+}
+
+func TestDiffContext(t *testing.T) {
+	a := []string{"a", "b", "c"}
+	b := []string{"a", "c", "d"}
+	d, minimal, err := DiffContext(context.Background(), a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !minimal || !reflect.DeepEqual(d, Diff(a, b)) {
+		t.Errorf("Expected the minimal diff %v, got %v (minimal: %v)", Diff(a, b), d, minimal)
+	}
+}
+
+// Once the deadline has passed, diffs with many changes should be found
+// quickly but not minimally.
+func TestDiffContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+
+	rng := rand.New(rand.NewSource(4))
+	a := randomLines(rng, 5000, 20)
+	b := randomLines(rng, 5000, 20)
+	testCases := []Option{
+		WithAlgorithm(AlgorithmMyers),
+		WithAlgorithm(AlgorithmLinearSpace),
+		WithAlgorithm(AlgorithmHistogram),
+		WithWeight(func(int) int { return 1 }),
+	}
+	for _, opt := range testCases {
+		d, minimal, err := DiffContext(ctx, a, b, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if minimal {
+			t.Error("Diff was reported as minimal")
+		}
+		if ra, rb := extractOriginals(d); !equalLines(ra, a) || !equalLines(rb, b) {
+			t.Error("Diff does not reconstruct the inputs")
+		}
+	}
+
+	// Small diffs still finish without the heuristic
+	b = append([]string{"x"}, a...)
+	d, minimal, err := DiffAlgorithmContext(ctx, len(a), len(b), func(i, j int) bool {
+		return a[i] == b[j]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !minimal || len(d) != len(b) {
+		t.Errorf("Expected a minimal diff with one change, got %v changes", len(d)-len(a))
+	}
+}
+
+// Giving up must stay within the inputs, however different their lengths.
+func TestDiffContextDeadlineLopsided(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+
+	rng := rand.New(rand.NewSource(5))
+	testCases := []struct {
+		a, b []string
+	}{
+		{randomLines(rng, 10000, 20), randomLines(rng, 100, 20)},
+		{randomLines(rng, 100, 20), randomLines(rng, 10000, 20)},
+		{randomLines(rng, 5000, 5000), randomLines(rng, 5000, 5000)},
+	}
+	for _, testCase := range testCases {
+		for _, algorithm := range []Algorithm{AlgorithmMyers, AlgorithmLinearSpace, AlgorithmPatience, AlgorithmHistogram} {
+			d, _, err := DiffContext(ctx, testCase.a, testCase.b, WithAlgorithm(algorithm))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ra, rb := extractOriginals(d); !equalLines(ra, testCase.a) || !equalLines(rb, testCase.b) {
+				t.Errorf("%v: Diff of %v and %v lines does not reconstruct the inputs", algorithm, len(testCase.a), len(testCase.b))
+			}
+		}
+	}
+}
@@ -12,11 +12,40 @@ import (
 // The output is an array of added, removed, and identical parts such that:
 // a = removed and identical lines
 // b = added and identical lines
-func Diff(a, b []string) []DiffPart {
-	d := DiffAlgorithm(len(a), len(b), func(i, j int) bool {
+//
+// Options like WithIgnoreCase change which lines are considered identical,
+// but the parts still hold the original lines. Identical parts hold the line
+// from a.
+func Diff(a, b []string, opts ...Option) []DiffPart {
+	o := newOptions(opts)
+	return toParts(a, b, o.diffLines(a, b))
+}
+
+// DiffSlices is like Diff, but works on slices of any comparable type. The
+// options that change how lines are compared have no effect.
+func DiffSlices[T comparable](a, b []T, opts ...Option) []Part[T] {
+	o := newOptions(opts)
+	d := o.diff(len(a), len(b), func(i, j int) bool {
 		return a[i] == b[j]
+	}, func() ([]int, []int) {
+		return intern(a, b)
 	})
-	result := make([]DiffPart, len(d))
+	return toParts(a, b, d)
+}
+
+// DiffFunc is like DiffSlices, but uses eq to decide whether two elements are
+// equal. Like DiffAlgorithm, AlgorithmPatience and AlgorithmHistogram need
+// n·m calls to eq.
+func DiffFunc[T any](a, b []T, eq func(T, T) bool, opts ...Option) []Part[T] {
+	equal := func(i, j int) bool {
+		return eq(a[i], b[j])
+	}
+	return toParts(a, b, DiffAlgorithm(len(a), len(b), equal, opts...))
+}
+
+// toParts attaches the values of a and b to an edit script.
+func toParts[T any](a, b []T, d []DiffAction) []Part[T] {
+	result := make([]Part[T], len(d))
 	var i, j int
 	for k, action := range d {
 		result[k].Action = action
@@ -34,7 +63,6 @@ func Diff(a, b []string) []DiffPart {
 		}
 	}
 	return result
-	// return WeightedDiff(a, b, func(_ string) int { return 1 })
 }
 
 type DiffAction int
@@ -43,6 +71,10 @@ const (
 	DiffIdentical DiffAction = iota
 	DiffAdded
 	DiffRemoved
+	// A removed line that was added back elsewhere, see DetectMoves
+	DiffMovedFrom
+	// An added line that was moved from elsewhere, see DetectMoves
+	DiffMovedTo
 )
 
 func (d DiffAction) String() string {
@@ -50,83 +82,114 @@ func (d DiffAction) String() string {
 		DiffAdded:     "Added",
 		DiffRemoved:   "Removed",
 		DiffIdentical: "Identical",
+		DiffMovedFrom: "MovedFrom",
+		DiffMovedTo:   "MovedTo",
 	}[d]
 }
 
-type DiffPart struct {
+// Part is an element of a diff, and whether it was added, removed, or is in
+// both sequences.
+type Part[T any] struct {
 	Action DiffAction
-	Value  string
+	Value  T
 }
 
-func (d DiffPart) String() string {
+func (d Part[T]) String() string {
 	return fmt.Sprintf("{%v: %v}", d.Action, d.Value)
 }
 
-func DiffAlgorithm(n, m int, equal func(i, j int) bool) []DiffAction {
-	if n == m {
-		same := true
-		for i := 0; i < n; i++ {
-			if !equal(i, i) {
-				same = false
-				break
-			}
-		}
-		if same {
-			return make([]DiffAction, n)
+// DiffPart is a line of a diff produced by Diff or LineDiff.
+type DiffPart = Part[string]
+
+// DiffAlgorithm finds an edit script turning a sequence of length n into a
+// sequence of length m, where equal(i, j) reports whether the ith element of
+// the first sequence matches the jth element of the second. The Myers
+// algorithms find a minimal script. Within each run of changes, removals are
+// placed before additions.
+//
+// AlgorithmPatience and AlgorithmHistogram need to know which elements are
+// equal to each other, which takes n·m calls to equal. Use Diff when the
+// elements are strings.
+func DiffAlgorithm(n, m int, equal func(i, j int) bool, opts ...Option) []DiffAction {
+	o := newOptions(opts)
+	return o.diff(n, m, equal, nil)
+}
+
+// diff runs the selected algorithm. ids returns an id for every element such
+// that equal elements share an id, or is nil if the elements can't be hashed.
+func (o options) diff(n, m int, equal func(i, j int) bool, ids func() (a, b []int)) []DiffAction {
+	hashable := ids != nil
+	if !hashable {
+		ids = func() ([]int, []int) {
+			return classify(n, m, equal)
 		}
 	}
 
-	// dp[i][j] = Number of matches possible for a[i..end] and b[j..end]
-	// The last row and column are for matching nothing against nothing (a[n..end] and b[n..end]) for sake of code simplicity
-	dp := make([][]int, n+1)
-	for i := 0; i <= n; i++ {
-		dp[i] = make([]int, m+1)
+	w := newWorkers(o.workers)
+	linear := func(n, m int, equal func(i, j int) bool) []DiffAction {
+		return myersLinearParallel(n, m, equal, o.budget, w)
 	}
 
-	for i := n - 1; i >= 0; i-- {
-		for j := m - 1; j >= 0; j-- {
-			matching := 0
-			if equal(i, j) {
-				matching = 1
-			}
-
-			opt := dp[i+1][j+1] + matching
-			if x := dp[i+1][j]; x > opt {
-				opt = x
-			}
-			if x := dp[i][j+1]; x > opt {
-				opt = x
-			}
-			dp[i][j] = opt
+	var d []DiffAction
+	switch {
+	case o.weight != nil:
+		d = weighted(n, m, equal, o.weight, o.budget)
+	case o.algorithm == AlgorithmPatience:
+		a, b := ids()
+		d = patience(a, b, o.budget, w)
+	case o.algorithm == AlgorithmHistogram:
+		a, b := ids()
+		d = histogram(a, b, o.budget, w)
+	case hashable:
+		// Compare ids instead of calling equal
+		a, b := ids()
+		equal := func(i, j int) bool {
+			return a[i] == b[j]
 		}
-	}
-
-	// Traceback
-	i := 0
-	j := 0
-	diff := []DiffAction{}
-	for i < n && j < m {
-		if equal(i, j) {
-			diff = append(diff, DiffIdentical)
-			i++
-			j++
-		} else if dp[i+1][j] < dp[i][j+1] { // In case of tie, prefer to put the removal first
-			diff = append(diff, DiffAdded)
-			j++
+		if o.algorithm == AlgorithmLinearSpace {
+			d = trimmed(n, m, equal, linear)
 		} else {
-			diff = append(diff, DiffRemoved)
-			i++
+			d = prepared(a, b, o.core(myers))
 		}
+	case o.algorithm == AlgorithmLinearSpace:
+		d = trimmed(n, m, equal, linear)
+	default:
+		d = trimmedPrefix(n, m, equal, o.core(myers))
 	}
+	return removalsFirst(d)
+}
 
-	// Process remaining removals
-	for ; i < n; i++ {
-		diff = append(diff, DiffRemoved)
-	}
-	// Process remaining additions
-	for ; j < m; j++ {
-		diff = append(diff, DiffAdded)
+// core binds one of the Myers algorithms to the budget of the diff.
+func (o options) core(algorithm func(n, m int, equal func(i, j int) bool, b *budget) []DiffAction) algorithmFunc {
+	return func(n, m int, equal func(i, j int) bool) []DiffAction {
+		return algorithm(n, m, equal, o.budget)
 	}
+}
 
-	return diff
+// removalsFirst reorders each run of consecutive changes so that the
+// removals come before the additions. This does not change which elements
+// are matched, only the order the edits are reported in.
+func removalsFirst(d []DiffAction) []DiffAction {
+	for i := 0; i < len(d); {
+		if d[i] == DiffIdentical {
+			i++
+			continue
+		}
+		j := i
+		removals := 0
+		for ; j < len(d) && d[j] != DiffIdentical; j++ {
+			if d[j] == DiffRemoved {
+				removals++
+			}
+		}
+		for k := i; k < j; k++ {
+			if k-i < removals {
+				d[k] = DiffRemoved
+			} else {
+				d[k] = DiffAdded
+			}
+		}
+		i = j
+	}
+	return d
 }
@@ -5,7 +5,9 @@
 package diff
 
 import (
+	"math/rand"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -101,3 +103,242 @@ func extractOriginals(d []DiffPart) (a, b []string) {
 	}
 	return
 }
+
+// Checks that DiffAlgorithm produces valid, minimal edit scripts by comparing
+// against a straightforward LCS computation.
+func TestDiffAlgorithmMinimal(t *testing.T) {
+	for _, algorithm := range []Algorithm{AlgorithmMyers, AlgorithmLinearSpace} {
+		testDiffMinimal(t, WithAlgorithm(algorithm))
+	}
+	testDiffMinimal(t, WithWeight(func(int) int { return 1 }))
+}
+
+func testDiffMinimal(t *testing.T, opts ...Option) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 200; iter++ {
+		a := randomLines(rng, rng.Intn(30), 4)
+		b := randomLines(rng, rng.Intn(30), 4)
+		d := Diff(a, b, opts...)
+
+		ra, rb := extractOriginals(d)
+		if !equalLines(ra, a) || !equalLines(rb, b) {
+			t.Fatalf("Diff of %v and %v does not reconstruct the inputs: %v", a, b, d)
+		}
+
+		matched := 0
+		for _, part := range d {
+			if part.Action == DiffIdentical {
+				matched++
+			}
+		}
+		if expected := lcsLength(a, b); matched != expected {
+			t.Errorf("Diff of %v and %v matched %v lines, expected %v", a, b, matched, expected)
+		}
+	}
+}
+
+// Small edits to large inputs should be fast, and must not allocate
+// anything proportional to n·m.
+func TestDiffAlgorithmLarge(t *testing.T) {
+	const n = 100000
+	a := make([]string, n)
+	for i := range a {
+		a[i] = strconv.Itoa(i)
+	}
+	b := append([]string{}, a...)
+	b[10] = "changed"
+	b = append(b[:n/2], b[n/2+5:]...)
+
+	d := Diff(a, b, WithAlgorithm(AlgorithmLinearSpace))
+	if !reflect.DeepEqual(d, Diff(a, b)) {
+		t.Error("Linear space diff differs from the default")
+	}
+
+	changes := 0
+	for _, part := range d {
+		if part.Action != DiffIdentical {
+			changes++
+		}
+	}
+	if changes != 7 {
+		t.Errorf("Expected 7 changes, got %v", changes)
+	}
+}
+
+func randomLines(rng *rand.Rand, n, alphabet int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = string(rune('a' + rng.Intn(alphabet)))
+	}
+	return lines
+}
+
+func equalLines(a, b []string) bool {
+	return len(a) == len(b) && (len(a) == 0 || reflect.DeepEqual(a, b))
+}
+
+func lcsLength(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else if dp[i+1][j] > dp[i][j+1] {
+				dp[i][j] = dp[i+1][j]
+			} else {
+				dp[i][j] = dp[i][j+1]
+			}
+		}
+	}
+	return dp[0][0]
+}
+
+// Patience diff should prefer matching a unique line over a longer run of
+// common lines.
+func TestDiffPatience(t *testing.T) {
+	a := []string{"func f() {", "}", "}", "}"}
+	b := []string{"}", "}", "}", "func f() {"}
+	expected := []DiffPart{
+		{DiffAdded, "}"},
+		{DiffAdded, "}"},
+		{DiffAdded, "}"},
+		{DiffIdentical, "func f() {"},
+		{DiffRemoved, "}"},
+		{DiffRemoved, "}"},
+		{DiffRemoved, "}"},
+	}
+	if result := Diff(a, b, WithAlgorithm(AlgorithmPatience)); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	// DiffAlgorithm has to find the unique lines through the callback
+	d := DiffAlgorithm(len(a), len(b), func(i, j int) bool {
+		return a[i] == b[j]
+	}, WithAlgorithm(AlgorithmPatience))
+	for k, action := range d {
+		if action != expected[k].Action {
+			t.Errorf("DiffAlgorithm gave %v, expected %v", d, expected)
+			break
+		}
+	}
+}
+
+// Algorithms that don't promise a minimal diff must still produce a diff that
+// reconstructs both inputs.
+func TestDiffValid(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for _, algorithm := range []Algorithm{AlgorithmPatience, AlgorithmHistogram} {
+		for iter := 0; iter < 200; iter++ {
+			a := randomLines(rng, rng.Intn(40), 8)
+			b := randomLines(rng, rng.Intn(40), 8)
+			d := Diff(a, b, WithAlgorithm(algorithm))
+			if ra, rb := extractOriginals(d); !equalLines(ra, a) || !equalLines(rb, b) {
+				t.Fatalf("%v diff of %v and %v does not reconstruct the inputs: %v", algorithm, a, b, d)
+			}
+		}
+	}
+}
+
+// Histogram diff should split on the rarest line, matching git diff --histogram.
+func TestDiffHistogram(t *testing.T) {
+	a := strings.Split("0 4 3 1 5 0 5 5 2 6 0 1 2 0 2", " ")
+	b := strings.Split("4 5 0 4 6 5 0 5 5 2 0 1 2", " ")
+	expected := []DiffPart{
+		{DiffAdded, "4"},
+		{DiffAdded, "5"},
+		{DiffIdentical, "0"},
+		{DiffIdentical, "4"},
+		{DiffRemoved, "3"},
+		{DiffRemoved, "1"},
+		{DiffRemoved, "5"},
+		{DiffRemoved, "0"},
+		{DiffRemoved, "5"},
+		{DiffRemoved, "5"},
+		{DiffRemoved, "2"},
+		{DiffIdentical, "6"},
+		{DiffAdded, "5"},
+		{DiffAdded, "0"},
+		{DiffAdded, "5"},
+		{DiffAdded, "5"},
+		{DiffAdded, "2"},
+		{DiffIdentical, "0"},
+		{DiffIdentical, "1"},
+		{DiffIdentical, "2"},
+		{DiffRemoved, "0"},
+		{DiffRemoved, "2"},
+	}
+	if result := Diff(a, b, WithAlgorithm(AlgorithmHistogram)); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+func TestDiffSlices(t *testing.T) {
+	result := DiffSlices([]byte("kitten"), []byte("sitting"))
+	expected := []Part[byte]{
+		{DiffRemoved, 'k'},
+		{DiffAdded, 's'},
+		{DiffIdentical, 'i'},
+		{DiffIdentical, 't'},
+		{DiffIdentical, 't'},
+		{DiffRemoved, 'e'},
+		{DiffAdded, 'i'},
+		{DiffIdentical, 'n'},
+		{DiffAdded, 'g'},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	for _, algorithm := range []Algorithm{AlgorithmPatience, AlgorithmHistogram} {
+		if result := DiffSlices([]byte("kitten"), []byte("sitting"), WithAlgorithm(algorithm)); len(result) != len(expected) {
+			t.Errorf("%v: Expected %v parts, got %v", algorithm, len(expected), result)
+		}
+	}
+}
+
+func TestDiffFunc(t *testing.T) {
+	type token struct {
+		kind, text string
+	}
+	a := []token{{"ident", "x"}, {"op", "+"}, {"ident", "y"}}
+	b := []token{{"ident", "X"}, {"op", "-"}, {"ident", "y"}}
+	result := DiffFunc(a, b, func(x, y token) bool {
+		return x.kind == y.kind && strings.EqualFold(x.text, y.text)
+	})
+	expected := []Part[token]{
+		{DiffIdentical, token{"ident", "x"}},
+		{DiffRemoved, token{"op", "+"}},
+		{DiffAdded, token{"op", "-"}},
+		{DiffIdentical, token{"ident", "y"}},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+// Weighing lines by length should prefer matching one long line over several
+// short ones.
+func TestWeightedDiff(t *testing.T) {
+	a := []string{"}", "}", "}", "return longMeaningfulLine"}
+	b := []string{"return longMeaningfulLine", "}", "}", "}"}
+	expected := []DiffPart{
+		{DiffRemoved, "}"},
+		{DiffRemoved, "}"},
+		{DiffRemoved, "}"},
+		{DiffIdentical, "return longMeaningfulLine"},
+		{DiffAdded, "}"},
+		{DiffAdded, "}"},
+		{DiffAdded, "}"},
+	}
+	if result := WeightedDiff(a, b, func(s string) int { return len(s) }); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	// Unit weights match the most lines
+	if result := WeightedDiff(a, b, func(string) int { return 1 }); !reflect.DeepEqual(result, Diff(a, b)) {
+		t.Errorf("Expected %v, got %v", Diff(a, b), result)
+	}
+}
@@ -0,0 +1,152 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package gotokens diffs Go source files token by token, so that changes
+// that only affect formatting, like those made by gofmt, are ignored.
+package gotokens
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+
+	"github.com/wk-y/diff"
+)
+
+// A Token is a token of Go source code and where it is.
+type Token struct {
+	Tok  token.Token
+	Text string // The source text of the token
+	Pos  token.Position
+	End  token.Position // Just after the last character of the token
+}
+
+// Tokenize splits Go source code into tokens, including comments.
+// Semicolons are left out, since where they are depends on line breaks.
+func Tokenize(src []byte) ([]Token, error) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+
+	var errs scanner.ErrorList
+	var s scanner.Scanner
+	s.Init(file, src, errs.Add, scanner.ScanComments)
+
+	tokens := []Token{}
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON {
+			continue
+		}
+
+		// The literal is empty for operators and keywords, and comments
+		// can have carriage returns removed, so take the text from src
+		start := file.Offset(pos)
+		end := start + len(lit)
+		if lit == "" {
+			end = start + len(tok.String())
+		}
+		if tok == token.COMMENT {
+			end = commentEnd(src, start)
+		}
+		tokens = append(tokens, Token{
+			Tok:  tok,
+			Text: string(src[start:end]),
+			Pos:  file.Position(pos),
+			End:  file.Position(file.Pos(end)),
+		})
+	}
+
+	errs.Sort()
+	return tokens, errs.Err()
+}
+
+// commentEnd returns the offset just after the comment starting at start.
+func commentEnd(src []byte, start int) int {
+	end := start + 2
+	if src[start+1] == '/' {
+		for end < len(src) && src[end] != '\n' {
+			end++
+		}
+		if end > start+2 && src[end-1] == '\r' {
+			end--
+		}
+		return end
+	}
+	for end+1 < len(src) && !(src[end] == '*' && src[end+1] == '/') {
+		end++
+	}
+	if end+1 < len(src) {
+		end += 2
+	} else {
+		end = len(src)
+	}
+	return end
+}
+
+// A Range is a part of a source file, from Start up to End.
+type Range struct {
+	Start, End token.Position
+}
+
+// String returns the range as line:column-line:column.
+func (r Range) String() string {
+	return fmt.Sprintf("%v:%v-%v:%v", r.Start.Line, r.Start.Column, r.End.Line, r.End.Column)
+}
+
+// A Change is a run of tokens that were removed from a and added in b. If
+// no tokens were removed, A is the empty range where the tokens were
+// inserted, and likewise for B.
+type Change struct {
+	A, B             Range
+	ATokens, BTokens []Token
+}
+
+// Diff tokenizes a and b, diffs the tokens with diff.DiffFunc, and returns
+// the changes with where they are in each file. Tokens are compared by
+// their kind and text, so white space, line breaks and semicolons are
+// ignored.
+func Diff(a, b []byte, opts ...diff.Option) ([]Change, error) {
+	aTokens, err := Tokenize(a)
+	if err != nil {
+		return nil, err
+	}
+	bTokens, err := Tokenize(b)
+	if err != nil {
+		return nil, err
+	}
+
+	d := diff.DiffFunc(aTokens, bTokens, func(x, y Token) bool {
+		return x.Tok == y.Tok && x.Text == y.Text
+	}, opts...)
+
+	changes := []Change{}
+	for _, op := range diff.OpsFromParts(d) {
+		if op.Kind == diff.OpEqual {
+			continue
+		}
+		changes = append(changes, Change{
+			A:       tokenRange(aTokens, op.AStart, op.AEnd),
+			B:       tokenRange(bTokens, op.BStart, op.BEnd),
+			ATokens: aTokens[op.AStart:op.AEnd],
+			BTokens: bTokens[op.BStart:op.BEnd],
+		})
+	}
+	return changes, nil
+}
+
+// tokenRange returns the range covered by tokens[start:end]. An empty range
+// is placed just after the token before it.
+func tokenRange(tokens []Token, start, end int) Range {
+	switch {
+	case start < end:
+		return Range{tokens[start].Pos, tokens[end-1].End}
+	case start > 0:
+		return Range{tokens[start-1].End, tokens[start-1].End}
+	}
+	first := token.Position{Offset: 0, Line: 1, Column: 1}
+	return Range{first, first}
+}
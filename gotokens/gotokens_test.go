@@ -0,0 +1,84 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package gotokens
+
+import (
+	"testing"
+)
+
+func TestDiffFormatting(t *testing.T) {
+	a := "package p\nfunc f(x int) int { return x+1; }\n"
+	b := "package p\n\nfunc f(x int) int {\n\treturn x + 1\n}\n"
+	changes, err := Diff([]byte(a), []byte(b))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v", changes)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	for _, test := range []struct {
+		a, b   string
+		ranges []string // The A and B range of each change
+		texts  []string // The source text of each range
+	}{
+		{
+			"package p\nvar x = f(1)\n",
+			"package p\n\nvar x = g(1,\n\t2)\n",
+			[]string{"2:9-2:10", "3:9-3:10", "2:12-2:12", "3:12-4:3"},
+			[]string{"f", "g", "", ",\n\t2"},
+		},
+		{
+			"package p // p\n",
+			"package p /* p */\n",
+			[]string{"1:11-1:15", "1:11-1:18"},
+			[]string{"// p", "/* p */"},
+		},
+		{
+			"package p\n",
+			"package p\nvar x int\n",
+			[]string{"1:10-1:10", "2:1-2:10"},
+			[]string{"", "var x int"},
+		},
+		{
+			"var x",
+			"package p\nvar x",
+			[]string{"1:1-1:1", "1:1-1:10"},
+			[]string{"", "package p"},
+		},
+	} {
+		changes, err := Diff([]byte(test.a), []byte(test.b))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			continue
+		}
+		ranges := []string{}
+		texts := []string{}
+		for _, change := range changes {
+			ranges = append(ranges, change.A.String(), change.B.String())
+			texts = append(texts,
+				test.a[change.A.Start.Offset:change.A.End.Offset],
+				test.b[change.B.Start.Offset:change.B.End.Offset],
+			)
+		}
+		if len(ranges) != len(test.ranges) {
+			t.Errorf("Expected %v, got %v", test.ranges, ranges)
+			continue
+		}
+		for i := range ranges {
+			if ranges[i] != test.ranges[i] || texts[i] != test.texts[i] {
+				t.Errorf("Expected %v %q, got %v %q", test.ranges[i], test.texts[i], ranges[i], texts[i])
+			}
+		}
+	}
+}
+
+func TestTokenizeError(t *testing.T) {
+	if _, err := Tokenize([]byte("package p\nvar s = \"unterminated\n")); err == nil {
+		t.Errorf("Expected an error for an unterminated string")
+	}
+}
@@ -0,0 +1,159 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+// Elements that occur more often than this in a region are not used as
+// anchors. Matches git's limit.
+const histogramMaxChain = 64
+
+// histogram implements the histogram diff from git (xdiff/xhistogram.c).
+// Like patience diff it splits the inputs around an anchor, but instead of
+// requiring the anchor to be unique it picks the longest common run that
+// contains the lowest occurrence count in a. Regions where every common
+// element is too frequent fall back to Myers' algorithm. The regions on
+// either side of an anchor can be diffed in parallel by w.
+func histogram(a, b []int, budget *budget, w *workers) []DiffAction {
+	h := histogramDiff{a: a, b: b, budget: budget, workers: w, diff: make([]DiffAction, 0, len(a)+len(b))}
+	h.compare(0, len(a), 0, len(b))
+	return h.diff
+}
+
+type histogramDiff struct {
+	a, b    []int // Element ids, equal elements share an id
+	budget  *budget
+	workers *workers
+	diff    []DiffAction
+}
+
+func (h *histogramDiff) compare(aLo, aHi, bLo, bHi int) {
+	h.budget.check()
+
+	if aLo == aHi || bLo == bHi {
+		h.changed(aLo, aHi, bLo, bHi)
+		return
+	}
+
+	as, ae, bs, be, found, fallback := h.findLCS(aLo, aHi, bLo, bHi)
+	switch {
+	case fallback:
+		h.diff = append(h.diff, myers(aHi-aLo, bHi-bLo, func(i, j int) bool {
+			return h.a[aLo+i] == h.b[bLo+j]
+		}, h.budget)...)
+	case !found:
+		h.changed(aLo, aHi, bLo, bHi)
+	case h.workers != nil && as-aLo+bs-bLo >= parallelMinSize:
+		before := histogramDiff{a: h.a, b: h.b, budget: h.budget, workers: h.workers}
+		wait := h.workers.fork(as-aLo+bs-bLo, func() {
+			before.compare(aLo, as, bLo, bs)
+		})
+		after := histogramDiff{a: h.a, b: h.b, budget: h.budget, workers: h.workers}
+		after.compare(ae, aHi, be, bHi)
+		wait()
+
+		h.diff = append(h.diff, before.diff...)
+		for i := as; i < ae; i++ {
+			h.diff = append(h.diff, DiffIdentical)
+		}
+		h.diff = append(h.diff, after.diff...)
+	default:
+		h.compare(aLo, as, bLo, bs)
+		for i := as; i < ae; i++ {
+			h.diff = append(h.diff, DiffIdentical)
+		}
+		h.compare(ae, aHi, be, bHi)
+	}
+}
+
+// changed marks a whole region as removed and added.
+func (h *histogramDiff) changed(aLo, aHi, bLo, bHi int) {
+	for ; aLo < aHi; aLo++ {
+		h.diff = append(h.diff, DiffRemoved)
+	}
+	for ; bLo < bHi; bLo++ {
+		h.diff = append(h.diff, DiffAdded)
+	}
+}
+
+// findLCS picks the common run a[as:ae] == b[bs:be] to split the region on.
+// fallback is set when the region has common elements, but all of them are
+// too frequent to be used.
+func (h *histogramDiff) findLCS(aLo, aHi, bLo, bHi int) (as, ae, bs, be int, found, fallback bool) {
+	// Count the occurrences of each element of a, and chain each occurrence
+	// to the next one.
+	counts := map[int]int{}
+	first := map[int]int{}
+	next := make([]int, aHi-aLo)
+	for i := aHi - 1; i >= aLo; i-- {
+		id := h.a[i]
+		if j, ok := first[id]; ok {
+			next[i-aLo] = j
+		} else {
+			next[i-aLo] = -1
+		}
+		first[id] = i
+		counts[id]++
+	}
+
+	// Like git, a match has to be longer than one element to win without
+	// having a lower count.
+	bestLength := 1
+	bestCount := histogramMaxChain + 1
+	hasCommon := false
+	for bi := bLo; bi < bHi; {
+		bNext := bi + 1
+		id := h.b[bi]
+		count, ok := counts[id]
+		if ok {
+			hasCommon = true
+		}
+		if !ok || count > bestCount {
+			bi = bNext
+			continue
+		}
+
+		for ai := first[id]; ai >= 0; {
+			// Extend the match in both directions, keeping track of the
+			// lowest occurrence count in it
+			s, t := ai, bi
+			e, f := ai+1, bi+1
+			rc := count
+			for aLo < s && bLo < t && h.a[s-1] == h.b[t-1] {
+				s--
+				t--
+				if rc > 1 && counts[h.a[s]] < rc {
+					rc = counts[h.a[s]]
+				}
+			}
+			for e < aHi && f < bHi && h.a[e] == h.b[f] {
+				if rc > 1 && counts[h.a[e]] < rc {
+					rc = counts[h.a[e]]
+				}
+				e++
+				f++
+			}
+
+			if bNext < f {
+				bNext = f
+			}
+			if bestLength < e-s || rc < bestCount {
+				as, ae, bs, be = s, e, t, f
+				bestLength = e - s
+				bestCount = rc
+				found = true
+			}
+
+			// Skip occurrences already covered by this match
+			ai = next[ai-aLo]
+			for ai >= 0 && ai < e {
+				ai = next[ai-aLo]
+			}
+		}
+
+		bi = bNext
+	}
+
+	fallback = hasCommon && bestCount > histogramMaxChain
+	return
+}
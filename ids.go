@@ -0,0 +1,65 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+// intern gives each distinct element of a and b an integer id, so that
+// algorithms can count and compare elements without comparing the values.
+func intern[T comparable](a, b []T) (aIDs, bIDs []int) {
+	ids := map[T]int{}
+	convert := func(values []T) []int {
+		result := make([]int, len(values))
+		for i, value := range values {
+			id, ok := ids[value]
+			if !ok {
+				id = len(ids)
+				ids[value] = id
+			}
+			result[i] = id
+		}
+		return result
+	}
+	return convert(a), convert(b)
+}
+
+// classify gives ids to elements using only the equal callback of
+// DiffAlgorithm. Elements of a that equal no element of b are never matched,
+// so they just get ids of their own. This takes n·m comparisons, so callers
+// that can hash their elements should intern them instead.
+func classify(n, m int, equal func(i, j int) bool) (aIDs, bIDs []int) {
+	aIDs = make([]int, n)
+	for i := range aIDs {
+		aIDs[i] = -1
+	}
+	bIDs = make([]int, m)
+	next := 0
+	for j := range bIDs {
+		bIDs[j] = -1
+		for i := range aIDs {
+			if !equal(i, j) {
+				continue
+			}
+			if aIDs[i] < 0 {
+				if bIDs[j] < 0 {
+					bIDs[j] = next
+					next++
+				}
+				aIDs[i] = bIDs[j]
+			} else {
+				bIDs[j] = aIDs[i]
+			}
+		}
+		if bIDs[j] < 0 {
+			bIDs[j] = next
+			next++
+		}
+	}
+	for i := range aIDs {
+		if aIDs[i] < 0 {
+			aIDs[i] = next
+			next++
+		}
+	}
+	return
+}
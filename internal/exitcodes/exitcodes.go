@@ -6,3 +6,8 @@ package exitcodes
 
 const IoError = 74
 const UsageError = 64
+
+// Exit codes of diff3, the same as GNU diff3's
+const NoConflicts = 0
+const Conflicts = 1
+const Trouble = 2
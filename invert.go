@@ -0,0 +1,42 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+// Invert turns a diff of a and b into a diff of b and a, by swapping added
+// and removed parts, and moved from and moved to parts. Within each run of
+// changes, the removals are put back before the additions, keeping their
+// order. Identical parts are left as they are, so if options like
+// WithIgnoreCase made lines that differ identical, they hold the lines of a.
+func Invert[T any](d []Part[T]) []Part[T] {
+	result := make([]Part[T], 0, len(d))
+	for i := 0; i < len(d); {
+		if d[i].Action == DiffIdentical {
+			result = append(result, d[i])
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(d) && d[j].Action != DiffIdentical {
+			j++
+		}
+		var added []Part[T]
+		for _, part := range d[i:j] {
+			switch part.Action {
+			case DiffAdded:
+				result = append(result, Part[T]{DiffRemoved, part.Value})
+			case DiffMovedTo:
+				result = append(result, Part[T]{DiffMovedFrom, part.Value})
+			case DiffRemoved:
+				added = append(added, Part[T]{DiffAdded, part.Value})
+			case DiffMovedFrom:
+				added = append(added, Part[T]{DiffMovedTo, part.Value})
+			}
+		}
+		result = append(result, added...)
+		i = j
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestInvert(t *testing.T) {
+	d := []DiffPart{
+		{DiffIdentical, "a"},
+		{DiffRemoved, "b"},
+		{DiffAdded, "x"},
+		{DiffAdded, "y"},
+		{DiffIdentical, "c"},
+		{DiffMovedFrom, "m"},
+		{DiffAdded, "z"},
+		{DiffIdentical, "d"},
+		{DiffMovedTo, "m"},
+	}
+	expected := []DiffPart{
+		{DiffIdentical, "a"},
+		{DiffRemoved, "x"},
+		{DiffRemoved, "y"},
+		{DiffAdded, "b"},
+		{DiffIdentical, "c"},
+		{DiffRemoved, "z"},
+		{DiffMovedTo, "m"},
+		{DiffIdentical, "d"},
+		{DiffMovedFrom, "m"},
+	}
+	if result := Invert(d); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+func TestInvertRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		a := make([]string, r.Intn(30))
+		for j := range a {
+			a[j] = string(rune('a' + r.Intn(4)))
+		}
+		b := make([]string, r.Intn(30))
+		for j := range b {
+			b[j] = string(rune('a' + r.Intn(4)))
+		}
+
+		// Inverting a diff gives a diff of b and a, and inverting it again
+		// gives the diff back
+		d := Diff(a, b)
+		inverted := Invert(d)
+		removed, added := []string{}, []string{}
+		for _, part := range inverted {
+			if part.Action != DiffAdded {
+				removed = append(removed, part.Value)
+			}
+			if part.Action != DiffRemoved {
+				added = append(added, part.Value)
+			}
+		}
+		if !reflect.DeepEqual(removed, b) || !reflect.DeepEqual(added, a) {
+			t.Fatalf("Expected a diff of %v and %v, got %v", b, a, inverted)
+		}
+		if result := Invert(inverted); !reflect.DeepEqual(result, d) {
+			t.Fatalf("Expected %v, got %v", d, result)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package jsondiff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Apply applies a patch to a document and returns the result. The document
+// is not modified. If an operation fails, Apply returns an error saying
+// which one, and no result.
+func Apply(doc interface{}, patch Patch) (interface{}, error) {
+	doc = clone(doc)
+	for i, op := range patch {
+		var err error
+		doc, err = apply(doc, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %v (%v %v): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+func apply(doc interface{}, op Operation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add":
+		return add(doc, path, clone(op.Value))
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+	case "replace":
+		if len(path) == 0 {
+			return clone(op.Value), nil
+		}
+		doc, _, err := remove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, clone(op.Value))
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if op.Op == "move" {
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("cannot move %v into itself", op.From)
+			}
+			doc, value, err = remove(doc, from)
+		} else {
+			value, err = get(doc, from)
+			value = clone(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "test":
+		value, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !Equal(value, op.Value) {
+			return nil, fmt.Errorf("test failed")
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// parsePointer splits a JSON Pointer into its unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON Pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = unescape(token)
+	}
+	return tokens, nil
+}
+
+// index parses an array index. If end is true, "-" and the length of the
+// array are allowed, meaning the end of the array.
+func index(token string, array []interface{}, end bool) (int, error) {
+	i, err := strconv.Atoi(token)
+	if token == "-" && end {
+		i, err = len(array), nil
+	}
+	if err != nil || (token != "0" && strings.HasPrefix(token, "0")) || strings.HasPrefix(token, "+") {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i < 0 || i > len(array) || (i == len(array) && !end) {
+		return 0, fmt.Errorf("array index %v out of range", i)
+	}
+	return i, nil
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := doc.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("no member %q", token)
+			}
+			doc = value
+		case []interface{}:
+			i, err := index(token, container, false)
+			if err != nil {
+				return nil, err
+			}
+			doc = container[i]
+		default:
+			return nil, fmt.Errorf("cannot index %T with %q", doc, token)
+		}
+	}
+	return doc, nil
+}
+
+// update calls f with the container holding the last token of path and that
+// token, and puts the container f returns in its place.
+func update(doc interface{}, path []string, f func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	container, err := f(parent, path[len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	if len(path) == 1 {
+		return container, nil
+	}
+
+	// Only arrays change identity when they grow or shrink
+	grandparent, _ := get(doc, path[:len(path)-2])
+	switch grandparent := grandparent.(type) {
+	case map[string]interface{}:
+		grandparent[path[len(path)-2]] = container
+	case []interface{}:
+		i, _ := index(path[len(path)-2], grandparent, false)
+		grandparent[i] = container
+	}
+	return doc, nil
+}
+
+// add adds value at path, replacing an object member or inserting into an
+// array.
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(doc, path, func(container interface{}, token string) (interface{}, error) {
+		switch container := container.(type) {
+		case map[string]interface{}:
+			container[token] = value
+			return container, nil
+		case []interface{}:
+			i, err := index(token, container, true)
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[i+1:], container[i:])
+			container[i] = value
+			return container, nil
+		}
+		return nil, fmt.Errorf("cannot add %q to %T", token, container)
+	})
+}
+
+// remove removes the value at path and returns it.
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the whole document")
+	}
+	var removed interface{}
+	doc, err := update(doc, path, func(container interface{}, token string) (interface{}, error) {
+		switch container := container.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("no member %q", token)
+			}
+			removed = value
+			delete(container, token)
+			return container, nil
+		case []interface{}:
+			i, err := index(token, container, false)
+			if err != nil {
+				return nil, err
+			}
+			removed = container[i]
+			return append(container[:i], container[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove %q from %T", token, container)
+	})
+	return doc, removed, err
+}
+
+// clone makes a deep copy of a JSON value.
+func clone(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, member := range value {
+			result[key] = clone(member)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, element := range value {
+			result[i] = clone(element)
+		}
+		return result
+	}
+	return value
+}
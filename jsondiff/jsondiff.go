@@ -0,0 +1,360 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package jsondiff compares JSON documents structurally, and describes and
+// applies the differences as RFC 6902 JSON Patches or RFC 7386 JSON Merge
+// Patches.
+//
+// Documents are values as decoded by encoding/json into an interface{}:
+// map[string]interface{}, []interface{}, string, float64 or json.Number,
+// bool and nil.
+package jsondiff
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wk-y/diff"
+)
+
+// An Operation is an operation of a JSON Patch. Op is one of "add",
+// "remove", "replace", "move", "copy" and "test". From is only used by move
+// and copy, and Value by add, replace and test.
+type Operation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON encodes the operation with only the members its kind uses, so
+// that a null value is still included.
+func (op Operation) MarshalJSON() ([]byte, error) {
+	switch op.Op {
+	case "add", "replace", "test":
+		return json.Marshal(struct {
+			Op    string      `json:"op"`
+			Path  string      `json:"path"`
+			Value interface{} `json:"value"`
+		}{op.Op, op.Path, op.Value})
+	case "move", "copy":
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			From string `json:"from"`
+			Path string `json:"path"`
+		}{op.Op, op.From, op.Path})
+	}
+	return json.Marshal(struct {
+		Op   string `json:"op"`
+		Path string `json:"path"`
+	}{op.Op, op.Path})
+}
+
+// A Patch is a JSON Patch, a list of operations applied in order.
+type Patch []Operation
+
+// Equal reports whether two JSON values are the same. Object members can be
+// in any order.
+func Equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			other, ok := b[key]
+			if !ok || !Equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !Equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	switch b.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return a == b
+}
+
+// Diff returns a patch that turns a into b. Objects are compared member by
+// member, and a member that was removed and added back under another name
+// becomes a move. Arrays are aligned with diff.DiffSlices, so opts choose
+// the algorithm, and elements that were removed and added back elsewhere in
+// the array become moves. A changed element or member that is an object or
+// array on both sides is diffed recursively, and otherwise replaced.
+//
+// The default diff.AlgorithmMyers keeps memory quadratic in the number of
+// changes, so large arrays that change a lot are better diffed with
+// diff.AlgorithmLinearSpace or diff.AlgorithmHistogram.
+func Diff(a, b interface{}, opts ...diff.Option) Patch {
+	d := differ{opts: opts, patch: Patch{}}
+	d.value("", a, b)
+	return d.patch
+}
+
+// differ collects the operations of a patch.
+type differ struct {
+	opts  []diff.Option
+	patch Patch
+}
+
+func (d *differ) value(path string, a, b interface{}) {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		if b, ok := b.(map[string]interface{}); ok {
+			d.object(path, a, b)
+			return
+		}
+	case []interface{}:
+		if b, ok := b.([]interface{}); ok {
+			d.array(path, a, b)
+			return
+		}
+	}
+	if !Equal(a, b) {
+		d.patch = append(d.patch, Operation{Op: "replace", Path: path, Value: b})
+	}
+}
+
+func (d *differ) object(path string, a, b map[string]interface{}) {
+	removed := []string{}
+	for _, key := range sortedKeys(a) {
+		if other, ok := b[key]; ok {
+			d.value(path+"/"+escape(key), a[key], other)
+		} else {
+			removed = append(removed, key)
+		}
+	}
+
+	for _, key := range sortedKeys(b) {
+		if _, ok := a[key]; ok {
+			continue
+		}
+		op := Operation{Op: "add", Path: path + "/" + escape(key), Value: b[key]}
+		for k, from := range removed {
+			if Equal(a[from], b[key]) {
+				op = Operation{Op: "move", From: path + "/" + escape(from), Path: op.Path}
+				removed = append(removed[:k], removed[k+1:]...)
+				break
+			}
+		}
+		d.patch = append(d.patch, op)
+	}
+
+	for _, key := range removed {
+		d.patch = append(d.patch, Operation{Op: "remove", Path: path + "/" + escape(key)})
+	}
+}
+
+func (d *differ) array(path string, a, b []interface{}) {
+	aIDs, bIDs := elementIDs(a, b)
+	ops := diff.OpsFromParts(diff.DiffSlices(aIDs, bIDs, d.opts...))
+
+	// Pair added elements with equal removed elements anywhere in the array,
+	// taking the removed elements in order
+	removed := map[int][]int{}
+	for _, op := range ops {
+		if op.Kind == diff.OpEqual {
+			continue
+		}
+		for i := op.AStart; i < op.AEnd; i++ {
+			removed[aIDs[i]] = append(removed[aIDs[i]], i)
+		}
+	}
+	moved := make([]bool, len(a))
+	movedFrom := map[int]int{}
+	for _, op := range ops {
+		if op.Kind == diff.OpEqual {
+			continue
+		}
+		for j := op.BStart; j < op.BEnd; j++ {
+			if queue := removed[bIDs[j]]; len(queue) > 0 {
+				moved[queue[0]] = true
+				movedFrom[j] = queue[0]
+				removed[bIDs[j]] = queue[1:]
+			}
+		}
+	}
+
+	// Each element of a and each element of b that isn't equal to one of a
+	// gets a slot, in the order they are in the array once the operations
+	// before them have run. The index of an element is the number of
+	// occupied slots before it.
+	aSlots := make([]int, len(a))
+	bSlots := make([]int, len(b))
+	slots := 0
+	for _, op := range ops {
+		for i := op.AStart; i < op.AEnd; i++ {
+			aSlots[i] = slots
+			slots++
+		}
+		if op.Kind == diff.OpEqual {
+			continue
+		}
+		for j := op.BStart; j < op.BEnd; j++ {
+			bSlots[j] = slots
+			slots++
+		}
+	}
+	occupied := newSlotCounts(slots)
+	for _, slot := range aSlots {
+		occupied.add(slot, 1)
+	}
+
+	at := func(slot int) string {
+		return path + "/" + strconv.Itoa(occupied.before(slot))
+	}
+	remove := func(i int) {
+		d.patch = append(d.patch, Operation{Op: "remove", Path: at(aSlots[i])})
+		occupied.add(aSlots[i], -1)
+	}
+	add := func(j int) {
+		occupied.add(bSlots[j], 1)
+		d.patch = append(d.patch, Operation{Op: "add", Path: at(bSlots[j]), Value: b[j]})
+	}
+
+	for _, op := range ops {
+		if op.Kind == diff.OpEqual {
+			continue
+		}
+
+		hasMoves := false
+		for i := op.AStart; i < op.AEnd; i++ {
+			hasMoves = hasMoves || moved[i]
+		}
+		for j := op.BStart; j < op.BEnd; j++ {
+			_, paired := movedFrom[j]
+			hasMoves = hasMoves || paired
+		}
+
+		if !hasMoves {
+			// Pair up the removed and added elements, so that changes inside
+			// them are diffed recursively
+			i, j := op.AStart, op.BStart
+			for ; i < op.AEnd && j < op.BEnd; i, j = i+1, j+1 {
+				d.value(at(aSlots[i]), a[i], b[j])
+			}
+			for ; i < op.AEnd; i++ {
+				remove(i)
+			}
+			for ; j < op.BEnd; j++ {
+				add(j)
+			}
+			continue
+		}
+
+		// Moved elements are left in place until they are moved
+		for i := op.AStart; i < op.AEnd; i++ {
+			if !moved[i] {
+				remove(i)
+			}
+		}
+		for j := op.BStart; j < op.BEnd; j++ {
+			i, paired := movedFrom[j]
+			if !paired {
+				add(j)
+				continue
+			}
+			from := at(aSlots[i])
+			occupied.add(aSlots[i], -1)
+			occupied.add(bSlots[j], 1)
+			if to := at(bSlots[j]); from != to {
+				d.patch = append(d.patch, Operation{Op: "move", From: from, Path: to})
+			}
+		}
+	}
+}
+
+// elementIDs numbers the elements of a and b so that elements are given the
+// same number if and only if they are Equal. Elements are looked up by their
+// encoding, which encoding/json gives with the members of objects sorted.
+func elementIDs(a, b []interface{}) (aIDs, bIDs []int) {
+	type element struct {
+		value interface{}
+		id    int
+	}
+	byEncoding := map[string][]element{}
+	ids := 0
+	id := func(value interface{}) int {
+		// Values that can't be encoded are all compared with Equal
+		encoding, _ := json.Marshal(value)
+		for _, e := range byEncoding[string(encoding)] {
+			if Equal(e.value, value) {
+				return e.id
+			}
+		}
+		e := element{value, ids}
+		byEncoding[string(encoding)] = append(byEncoding[string(encoding)], e)
+		ids++
+		return e.id
+	}
+
+	aIDs = make([]int, len(a))
+	for i, value := range a {
+		aIDs[i] = id(value)
+	}
+	bIDs = make([]int, len(b))
+	for j, value := range b {
+		bIDs[j] = id(value)
+	}
+	return aIDs, bIDs
+}
+
+// slotCounts is a Fenwick tree of how many elements are in each slot of an
+// array, so that the index of a slot can be found as elements are added and
+// removed.
+type slotCounts []int
+
+func newSlotCounts(n int) slotCounts {
+	return make(slotCounts, n+1)
+}
+
+// add adds delta to the count of slot.
+func (c slotCounts) add(slot, delta int) {
+	for k := slot + 1; k < len(c); k += k & -k {
+		c[k] += delta
+	}
+}
+
+// before returns the number of elements in the slots before slot.
+func (c slotCounts) before(slot int) int {
+	n := 0
+	for k := slot; k > 0; k -= k & -k {
+		n += c[k]
+	}
+	return n
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escape escapes a member name for use in a JSON Pointer.
+func escape(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+// unescape undoes escape.
+func unescape(token string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+}
@@ -0,0 +1,225 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/wk-y/diff"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	var value interface{}
+	if err := json.Unmarshal([]byte(s), &value); err != nil {
+		t.Fatalf("Invalid JSON %v: %v", s, err)
+	}
+	return value
+}
+
+func TestDiff(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected string
+	}{
+		{`{"a": 1}`, `{"a": 1}`, `[]`},
+		{`1`, `"1"`, `[{"op":"replace","path":"","value":"1"}]`},
+		{
+			`{"a": 1, "b": {"c": [1, 2]}, "d": null}`,
+			`{"a": 2, "b": {"c": [1, 2, 3]}, "e": null}`,
+			`[{"op":"replace","path":"/a","value":2},{"op":"add","path":"/b/c/2","value":3},{"op":"move","from":"/d","path":"/e"}]`,
+		},
+		{
+			`{"a/b": 1, "c~d": true}`,
+			`{"c~d": false}`,
+			`[{"op":"replace","path":"/c~0d","value":false},{"op":"remove","path":"/a~1b"}]`,
+		},
+		{
+			`[1, 2, 3, 4]`,
+			`[1, 3, 5, 4]`,
+			`[{"op":"remove","path":"/1"},{"op":"add","path":"/2","value":5}]`,
+		},
+		{
+			`[{"id": 1, "tags": ["x"]}, 2]`,
+			`[{"id": 1, "tags": ["y"]}, 2]`,
+			`[{"op":"replace","path":"/0/tags/0","value":"y"}]`,
+		},
+		{
+			`["a", "b", "c", "d"]`,
+			`["b", "c", "d", "a"]`,
+			`[{"op":"move","from":"/0","path":"/3"}]`,
+		},
+		{
+			`["a", "b", "c", "d"]`,
+			`["d", "a", "b", "c"]`,
+			`[{"op":"move","from":"/3","path":"/0"}]`,
+		},
+	} {
+		patch := Diff(decode(t, test.a), decode(t, test.b))
+		encoded, err := json.Marshal(patch)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			continue
+		}
+		if string(encoded) != test.expected {
+			t.Errorf("Expected %v, got %s", test.expected, encoded)
+		}
+	}
+}
+
+// Large arrays should be diffed without comparing every pair of elements
+func TestDiffLargeArray(t *testing.T) {
+	const n = 20000
+	a := make([]interface{}, n)
+	b := make([]interface{}, n)
+	for i := range a {
+		a[i] = float64(i)
+		b[n-1-i] = float64(i)
+	}
+
+	patch := Diff(a, b, diff.WithAlgorithm(diff.AlgorithmHistogram))
+	if len(patch) != n-1 {
+		t.Errorf("Expected %v moves, got %v operations", n-1, len(patch))
+	}
+	result, err := Apply(a, patch)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !Equal(result, b) {
+		t.Errorf("Applying the patch did not give the reversed array")
+	}
+}
+
+// Values with the same encoding that aren't Equal are not moves
+func TestDiffArrayNumbers(t *testing.T) {
+	a := []interface{}{1.0, "x"}
+	b := []interface{}{"x", json.Number("1")}
+	expected := `[{"op":"remove","path":"/0"},{"op":"add","path":"/1","value":1}]`
+	encoded, err := json.Marshal(Diff(a, b))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(encoded) != expected {
+		t.Errorf("Expected %v, got %s", expected, encoded)
+	}
+}
+
+func TestApply(t *testing.T) {
+	// Examples from RFC 6902, appendix A
+	for _, test := range []struct {
+		doc, patch, expected string
+	}{
+		{`{"foo": "bar"}`, `[{"op": "add", "path": "/baz", "value": "qux"}]`, `{"baz": "qux", "foo": "bar"}`},
+		{`{"foo": ["bar", "baz"]}`, `[{"op": "add", "path": "/foo/1", "value": "qux"}]`, `{"foo": ["bar", "qux", "baz"]}`},
+		{`{"baz": "qux", "foo": "bar"}`, `[{"op": "remove", "path": "/baz"}]`, `{"foo": "bar"}`},
+		{`{"foo": ["bar", "qux", "baz"]}`, `[{"op": "remove", "path": "/foo/1"}]`, `{"foo": ["bar", "baz"]}`},
+		{`{"baz": "qux", "foo": "bar"}`, `[{"op": "replace", "path": "/baz", "value": "boo"}]`, `{"baz": "boo", "foo": "bar"}`},
+		{
+			`{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			`[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			`{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{`{"foo": ["all", "grass", "cows", "eat"]}`, `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`, `{"foo": ["all", "cows", "eat", "grass"]}`},
+		{`{"foo": ["bar"]}`, `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`, `{"foo": ["bar", ["abc", "def"]]}`},
+		{`{"foo": null}`, `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`, `{"foo": null, "child": {"grandchild": {}}}`},
+		{`{"/": 9, "~1": 10}`, `[{"op": "test", "path": "/~01", "value": 10}]`, `{"/": 9, "~1": 10}`},
+		{`{"a": [1]}`, `[{"op": "copy", "from": "/a", "path": "/b"}, {"op": "add", "path": "/b/0", "value": 0}]`, `{"a": [1], "b": [0, 1]}`},
+		{`{"a": 1}`, `[{"op": "replace", "path": "", "value": [1]}]`, `[1]`},
+	} {
+		var patch Patch
+		if err := json.Unmarshal([]byte(test.patch), &patch); err != nil {
+			t.Fatalf("Invalid patch %v: %v", test.patch, err)
+		}
+		doc := decode(t, test.doc)
+		result, err := Apply(doc, patch)
+		if err != nil {
+			t.Errorf("Unexpected error applying %v: %v", test.patch, err)
+			continue
+		}
+		if expected := decode(t, test.expected); !Equal(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+		if original := decode(t, test.doc); !Equal(doc, original) {
+			t.Errorf("Expected the document to be unchanged, got %v", doc)
+		}
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	for _, test := range []struct {
+		doc, patch string
+	}{
+		{`{"foo": "bar"}`, `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`},
+		{`{"foo": ["bar", "baz"]}`, `[{"op": "add", "path": "/foo/3", "value": "qux"}]`},
+		{`{"foo": ["bar", "baz"]}`, `[{"op": "remove", "path": "/foo/01"}]`},
+		{`{"baz": "qux"}`, `[{"op": "test", "path": "/baz", "value": "bar"}]`},
+		{`{"baz": "qux"}`, `[{"op": "replace", "path": "/foo", "value": "bar"}]`},
+		{`{"a": {"b": 1}}`, `[{"op": "move", "from": "/a", "path": "/a/b/c"}]`},
+		{`{"a": 1}`, `[{"op": "frobnicate", "path": "/a"}]`},
+		{`{"a": 1}`, `[{"op": "remove", "path": "a"}]`},
+	} {
+		var patch Patch
+		if err := json.Unmarshal([]byte(test.patch), &patch); err != nil {
+			t.Fatalf("Invalid patch %v: %v", test.patch, err)
+		}
+		if _, err := Apply(decode(t, test.doc), patch); err == nil {
+			t.Errorf("Expected an error applying %v", test.patch)
+		}
+	}
+}
+
+// randomValue returns a random JSON value with small arrays and objects, so
+// that elements often repeat.
+func randomValue(r *rand.Rand, depth int) interface{} {
+	switch n := r.Intn(6); {
+	case n == 0 && depth > 0:
+		array := make([]interface{}, r.Intn(6))
+		for i := range array {
+			array[i] = randomValue(r, depth-1)
+		}
+		return array
+	case n == 1 && depth > 0:
+		object := map[string]interface{}{}
+		for i := r.Intn(4); i > 0; i-- {
+			object[fmt.Sprint(r.Intn(5))] = randomValue(r, depth-1)
+		}
+		return object
+	case n == 2:
+		return nil
+	default:
+		return float64(r.Intn(4))
+	}
+}
+
+func TestDiffApplyRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		a := randomValue(r, 3)
+		b := randomValue(r, 3)
+		if r.Intn(2) == 0 {
+			// Mostly similar arrays exercise moves and recursive diffs
+			array := []interface{}{}
+			for j := r.Intn(8); j > 0; j-- {
+				array = append(array, randomValue(r, 2))
+			}
+			shuffled := append([]interface{}{}, array...)
+			r.Shuffle(len(shuffled), func(i, j int) {
+				shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+			})
+			a, b = array, append(shuffled, randomValue(r, 2))
+		}
+
+		patch := Diff(a, b)
+		result, err := Apply(a, patch)
+		if err != nil {
+			t.Fatalf("Unexpected error applying %v to %v: %v", patch, a, err)
+		}
+		if !Equal(result, b) {
+			t.Fatalf("Expected %v, got %v from %v and patch %v", b, result, a, patch)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package jsondiff
+
+import "errors"
+
+// ErrNullMember is returned by MergePatch when the second document has an
+// object member that is null, which a merge patch can't set since null
+// means removing the member.
+var ErrNullMember = errors.New("merge patch cannot set an object member to null")
+
+// MergePatch returns an RFC 7386 JSON Merge Patch that turns a into b.
+// Objects are compared member by member, while any other changed value,
+// including an array, is replaced whole. ApplyMergePatch(a, patch) is equal
+// to b, unless b has a null object member outside of an array, in which
+// case MergePatch returns ErrNullMember.
+func MergePatch(a, b interface{}) (interface{}, error) {
+	bObject, ok := b.(map[string]interface{})
+	if !ok {
+		return clone(b), nil
+	}
+	aObject, ok := a.(map[string]interface{})
+	if !ok {
+		// Patching a non-object starts from an empty object
+		aObject = map[string]interface{}{}
+	}
+
+	patch := map[string]interface{}{}
+	for key := range aObject {
+		if _, ok := bObject[key]; !ok {
+			patch[key] = nil
+		}
+	}
+	for key, value := range bObject {
+		old, ok := aObject[key]
+		if ok && Equal(old, value) {
+			continue
+		}
+		if value == nil {
+			return nil, ErrNullMember
+		}
+		if _, isObject := old.(map[string]interface{}); !isObject {
+			old = nil
+		}
+		member, err := MergePatch(old, value)
+		if err != nil {
+			return nil, err
+		}
+		patch[key] = member
+	}
+	return patch, nil
+}
+
+// ApplyMergePatch applies an RFC 7386 JSON Merge Patch to a document and
+// returns the result. The document is not modified.
+func ApplyMergePatch(doc, patch interface{}) interface{} {
+	return applyMergePatch(clone(doc), patch)
+}
+
+// applyMergePatch is ApplyMergePatch, but may modify doc.
+func applyMergePatch(doc, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return clone(patch)
+	}
+	docObject, ok := doc.(map[string]interface{})
+	if !ok {
+		docObject = map[string]interface{}{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(docObject, key)
+		} else {
+			docObject[key] = applyMergePatch(docObject[key], value)
+		}
+	}
+	return docObject
+}
@@ -0,0 +1,98 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package jsondiff
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	// Examples from RFC 7386, appendix A
+	for _, test := range []struct {
+		doc, patch, expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	} {
+		doc := decode(t, test.doc)
+		result := ApplyMergePatch(doc, decode(t, test.patch))
+		if expected := decode(t, test.expected); !Equal(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+		if original := decode(t, test.doc); !Equal(doc, original) {
+			t.Errorf("Expected the document to be unchanged, got %v", doc)
+		}
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected string
+	}{
+		{`{"a":1,"b":{"c":2,"d":3}}`, `{"a":1,"b":{"c":2,"d":4}}`, `{"b":{"d":4}}`},
+		{`{"a":1,"b":2}`, `{"b":2,"c":[1]}`, `{"a":null,"c":[1]}`},
+		{`{"a":[1,2]}`, `{"a":[1,3]}`, `{"a":[1,3]}`},
+		{`{"a":"b"}`, `{"a":{"c":"d"}}`, `{"a":{"c":"d"}}`},
+		{`{"a":null}`, `{"a":null,"b":1}`, `{"b":1}`},
+		{`[1]`, `{"a":[null]}`, `{"a":[null]}`},
+		{`{"a":1}`, `null`, `null`},
+	} {
+		patch, err := MergePatch(decode(t, test.a), decode(t, test.b))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			continue
+		}
+		encoded, _ := json.Marshal(patch)
+		if string(encoded) != test.expected {
+			t.Errorf("Expected %v, got %s", test.expected, encoded)
+		}
+	}
+
+	for _, test := range []struct {
+		a, b string
+	}{
+		{`{"a":1}`, `{"a":null}`},
+		{`{}`, `{"a":{"b":null}}`},
+		{`[]`, `{"a":null}`},
+	} {
+		if _, err := MergePatch(decode(t, test.a), decode(t, test.b)); err != ErrNullMember {
+			t.Errorf("Expected %v, got %v", ErrNullMember, err)
+		}
+	}
+}
+
+func TestMergePatchRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		a := randomValue(r, 3)
+		b := randomValue(r, 3)
+		patch, err := MergePatch(a, b)
+		if err == ErrNullMember {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result := ApplyMergePatch(a, patch); !Equal(result, b) {
+			t.Fatalf("Expected %v, got %v from %v and patch %v", b, result, a, patch)
+		}
+	}
+}
@@ -8,6 +8,6 @@ import (
 	"github.com/wk-y/diff/internal/strutils"
 )
 
-func LineDiff(a, b string) []DiffPart {
-	return Diff(strutils.SplitLines(a), strutils.SplitLines(b))
+func LineDiff(a, b string, opts ...Option) []DiffPart {
+	return Diff(strutils.SplitLines(a), strutils.SplitLines(b), opts...)
 }
@@ -0,0 +1,212 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package merge combines two sets of changes to the same file, like diff3
+// and git merge-file.
+package merge
+
+import (
+	"github.com/wk-y/diff"
+)
+
+// RegionKind describes how the three versions of a region compare.
+type RegionKind int
+
+const (
+	// All three versions are the same
+	Unchanged RegionKind = iota
+	// Only ours changed the region
+	ChangedOurs
+	// Only theirs changed the region
+	ChangedTheirs
+	// Both made the same change
+	ChangedBoth
+	// Both changed the region in different ways
+	Conflict
+)
+
+func (k RegionKind) String() string {
+	return []string{
+		Unchanged:     "Unchanged",
+		ChangedOurs:   "ChangedOurs",
+		ChangedTheirs: "ChangedTheirs",
+		ChangedBoth:   "ChangedBoth",
+		Conflict:      "Conflict",
+	}[k]
+}
+
+// A Region is a part of a merge, with the lines of each version in it.
+type Region struct {
+	Kind               RegionKind
+	Base, Ours, Theirs []string
+	// Index of the first line of the region in each version
+	BaseStart, OursStart, TheirsStart int
+}
+
+// Merged returns the lines of the region once merged, or nil if the region is
+// a conflict.
+func (r Region) Merged() []string {
+	switch r.Kind {
+	case Unchanged, ChangedOurs, ChangedBoth:
+		return r.Ours
+	case ChangedTheirs:
+		return r.Theirs
+	}
+	return nil
+}
+
+// Merge combines the changes from base to ours and from base to theirs. The
+// result covers all three versions in order, and alternates between
+// unchanged regions and regions where at least one side made changes.
+//
+// Changes are found with diff.Diff, which opts are passed to. Lines that
+// both sides kept from base split the regions, so changes only conflict if
+// they touch the same lines of base or are inserted in the same place.
+func Merge(base, ours, theirs []string, opts ...diff.Option) []Region {
+	return MergeFunc(base, ours, theirs, func(a, b []string) []diff.DiffPart {
+		return diff.Diff(a, b, opts...)
+	})
+}
+
+// MergeFunc is like Merge, but finds the changes with diffLines, which
+// returns a diff from a to b.
+func MergeFunc(base, ours, theirs []string, diffLines func(a, b []string) []diff.DiffPart) []Region {
+	// For each line of base, where it is in ours and theirs, or -1
+	oursMatch := matches(base, ours, diffLines)
+	theirsMatch := matches(base, theirs, diffLines)
+
+	regions := []Region{}
+	add := func(kind RegionKind, i, j, k, iEnd, jEnd, kEnd int) {
+		if last := len(regions) - 1; last >= 0 && kind == Unchanged && regions[last].Kind == Unchanged {
+			regions[last].Base = base[regions[last].BaseStart:iEnd]
+			regions[last].Ours = ours[regions[last].OursStart:jEnd]
+			regions[last].Theirs = theirs[regions[last].TheirsStart:kEnd]
+			return
+		}
+		regions = append(regions, Region{
+			Kind:        kind,
+			Base:        base[i:iEnd],
+			Ours:        ours[j:jEnd],
+			Theirs:      theirs[k:kEnd],
+			BaseStart:   i,
+			OursStart:   j,
+			TheirsStart: k,
+		})
+	}
+
+	i, j, k := 0, 0, 0
+	for i < len(base) || j < len(ours) || k < len(theirs) {
+		if i < len(base) && oursMatch[i] == j && theirsMatch[i] == k {
+			add(Unchanged, i, j, k, i+1, j+1, k+1)
+			i, j, k = i+1, j+1, k+1
+			continue
+		}
+
+		// Find the next line of base that both sides kept
+		iEnd, jEnd, kEnd := i, len(ours), len(theirs)
+		for ; iEnd < len(base); iEnd++ {
+			if oursMatch[iEnd] >= 0 && theirsMatch[iEnd] >= 0 {
+				jEnd, kEnd = oursMatch[iEnd], theirsMatch[iEnd]
+				break
+			}
+		}
+
+		baseLines := base[i:iEnd]
+		oursLines := ours[j:jEnd]
+		theirsLines := theirs[k:kEnd]
+		var kind RegionKind
+		switch {
+		case equal(oursLines, baseLines):
+			kind = ChangedTheirs
+		case equal(theirsLines, baseLines):
+			kind = ChangedOurs
+		case equal(oursLines, theirsLines):
+			kind = ChangedBoth
+		default:
+			kind = Conflict
+		}
+		add(kind, i, j, k, iEnd, jEnd, kEnd)
+		i, j, k = iEnd, jEnd, kEnd
+	}
+
+	return regions
+}
+
+// matches returns where each line of base is in the diff from other to
+// base, or -1 if it isn't there. Like GNU diff3, other comes first, which
+// decides which lines are matched when there is a choice.
+func matches(base, other []string, diffLines func(a, b []string) []diff.DiffPart) []int {
+	result := make([]int, len(base))
+	var i, j int
+	for _, part := range diffLines(other, base) {
+		switch part.Action {
+		case diff.DiffIdentical:
+			result[j] = i
+			i++
+			j++
+		case diff.DiffRemoved:
+			i++
+		case diff.DiffAdded:
+			result[j] = -1
+			j++
+		}
+	}
+	return result
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// HasConflicts reports whether any of the regions is a conflict.
+func HasConflicts(regions []Region) bool {
+	for _, r := range regions {
+		if r.Kind == Conflict {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolution picks how Resolve settles conflicts.
+type Resolution int
+
+const (
+	// Keep our side of conflicts
+	ResolveOurs Resolution = iota
+	// Keep their side of conflicts
+	ResolveTheirs
+	// Keep both sides of conflicts, ours first
+	ResolveUnion
+)
+
+// Resolve returns the merged lines, settling any conflicts with resolution
+// instead of leaving conflict markers.
+func Resolve(regions []Region, resolution Resolution) []string {
+	result := []string{}
+	for _, r := range regions {
+		if r.Kind != Conflict {
+			result = append(result, r.Merged()...)
+			continue
+		}
+		switch resolution {
+		case ResolveOurs:
+			result = append(result, r.Ours...)
+		case ResolveTheirs:
+			result = append(result, r.Theirs...)
+		case ResolveUnion:
+			result = append(result, r.Ours...)
+			result = append(result, r.Theirs...)
+		}
+	}
+	return result
+}
@@ -0,0 +1,103 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package merge
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wk-y/diff/internal/strutils"
+)
+
+func TestMergeClean(t *testing.T) {
+	base := strutils.SplitLines("a\nb\nc\nd\ne\n")
+	ours := strutils.SplitLines("a\nB\nc\nd\ne\n")
+	theirs := strutils.SplitLines("a\nb\nc\nd\nE\nf\n")
+
+	regions := Merge(base, ours, theirs)
+	if HasConflicts(regions) {
+		t.Fatalf("Expected no conflicts, got %v", regions)
+	}
+	kinds := []RegionKind{}
+	for _, r := range regions {
+		kinds = append(kinds, r.Kind)
+	}
+	expectedKinds := []RegionKind{Unchanged, ChangedOurs, Unchanged, ChangedTheirs}
+	if !reflect.DeepEqual(kinds, expectedKinds) {
+		t.Errorf("Expected %v, got %v", expectedKinds, kinds)
+	}
+
+	expected := "a\nB\nc\nd\nE\nf\n"
+	if result := Render(regions, StyleMerge, Labels{}); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+	if result := strings.Join(Resolve(regions, ResolveUnion), ""); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestMergeConflict(t *testing.T) {
+	base := strutils.SplitLines("a\nb\nc\n")
+	ours := strutils.SplitLines("a\nx\ny\nz\nc\n")
+	theirs := strutils.SplitLines("a\nx\nw\nz\nc\n")
+	regions := Merge(base, ours, theirs)
+
+	expected := []Region{
+		{Kind: Unchanged, Base: base[:1], Ours: ours[:1], Theirs: theirs[:1]},
+		{Kind: Conflict, Base: base[1:2], Ours: ours[1:4], Theirs: theirs[1:4], BaseStart: 1, OursStart: 1, TheirsStart: 1},
+		{Kind: Unchanged, Base: base[2:], Ours: ours[4:], Theirs: theirs[4:], BaseStart: 2, OursStart: 4, TheirsStart: 4},
+	}
+	if !reflect.DeepEqual(regions, expected) {
+		t.Fatalf("Expected %v, got %v", expected, regions)
+	}
+
+	labels := Labels{Ours: "ours", Base: "base", Theirs: "theirs"}
+	testCases := []struct {
+		style    Style
+		expected string
+	}{
+		{StyleMerge, "a\n<<<<<<< ours\nx\ny\nz\n=======\nx\nw\nz\n>>>>>>> theirs\nc\n"},
+		{StyleDiff3, "a\n<<<<<<< ours\nx\ny\nz\n||||||| base\nb\n=======\nx\nw\nz\n>>>>>>> theirs\nc\n"},
+		{StyleZDiff3, "a\nx\n<<<<<<< ours\ny\n||||||| base\nb\n=======\nw\n>>>>>>> theirs\nz\nc\n"},
+	}
+	for _, testCase := range testCases {
+		if result := Render(regions, testCase.style, labels); result != testCase.expected {
+			t.Errorf("%v: Expected %q, got %q", testCase.style, testCase.expected, result)
+		}
+	}
+
+	resolutions := []struct {
+		resolution Resolution
+		expected   string
+	}{
+		{ResolveOurs, "a\nx\ny\nz\nc\n"},
+		{ResolveTheirs, "a\nx\nw\nz\nc\n"},
+		{ResolveUnion, "a\nx\ny\nz\nx\nw\nz\nc\n"},
+	}
+	for _, testCase := range resolutions {
+		if result := strings.Join(Resolve(regions, testCase.resolution), ""); result != testCase.expected {
+			t.Errorf("Expected %q, got %q", testCase.expected, result)
+		}
+	}
+}
+
+func TestMergeSameChange(t *testing.T) {
+	base := strutils.SplitLines("a\nb\n")
+	ours := strutils.SplitLines("a\nc\n")
+	regions := Merge(base, ours, ours)
+	if len(regions) != 2 || regions[1].Kind != ChangedBoth {
+		t.Errorf("Expected the change to be in both, got %v", regions)
+	}
+}
+
+// Conflict markers need to be on their own line.
+func TestRenderMissingNewline(t *testing.T) {
+	regions := Merge([]string{"a"}, []string{"b"}, []string{"c"})
+	expected := "<<<<<<<\nb\n=======\nc\n>>>>>>>\n"
+	if result := Render(regions, StyleMerge, Labels{}); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
@@ -0,0 +1,112 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package merge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Style selects how Render shows conflicts. The names are the same as git's
+// merge.conflictStyle.
+type Style int
+
+const (
+	// Ours and theirs, separated by =======
+	StyleMerge Style = iota
+	// Like StyleMerge, but with base after ||||||| between them
+	StyleDiff3
+	// Like StyleDiff3, but lines at the start or end of a conflict that
+	// ours and theirs have in common are moved out of it
+	StyleZDiff3
+)
+
+var styleNames = []string{
+	StyleMerge:  "merge",
+	StyleDiff3:  "diff3",
+	StyleZDiff3: "zdiff3",
+}
+
+func (s Style) String() string {
+	return styleNames[s]
+}
+
+// Set parses the name of a style, so that a Style can be used as a
+// flag.Value.
+func (s *Style) Set(name string) error {
+	for style, n := range styleNames {
+		if n == name {
+			*s = Style(style)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown conflict style %q", name)
+}
+
+// Labels are written after the conflict markers, usually the names of the
+// files. Markers have no label if it is empty.
+type Labels struct {
+	Ours, Base, Theirs string
+}
+
+// Render returns the merged lines as a string, with conflicts shown between
+// <<<<<<< and >>>>>>> markers.
+func Render(regions []Region, style Style, labels Labels) string {
+	var sb strings.Builder
+	writeLines := func(lines []string) {
+		for _, line := range lines {
+			sb.WriteString(line)
+		}
+	}
+	writeMarker := func(marker, label string) {
+		// Markers go on their own line even after a line without a newline
+		if s := sb.String(); s != "" && !strings.HasSuffix(s, "\n") {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(marker)
+		if label != "" {
+			sb.WriteString(" " + label)
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, r := range regions {
+		if r.Kind != Conflict {
+			writeLines(r.Merged())
+			continue
+		}
+
+		ours, theirs := r.Ours, r.Theirs
+		var suffix []string
+		if style == StyleZDiff3 {
+			prefix := 0
+			for prefix < len(ours) && prefix < len(theirs) && ours[prefix] == theirs[prefix] {
+				prefix++
+			}
+			writeLines(ours[:prefix])
+			ours, theirs = ours[prefix:], theirs[prefix:]
+
+			n := 0
+			for n < len(ours) && n < len(theirs) && ours[len(ours)-1-n] == theirs[len(theirs)-1-n] {
+				n++
+			}
+			suffix = ours[len(ours)-n:]
+			ours, theirs = ours[:len(ours)-n], theirs[:len(theirs)-n]
+		}
+
+		writeMarker("<<<<<<<", labels.Ours)
+		writeLines(ours)
+		if style != StyleMerge {
+			writeMarker("|||||||", labels.Base)
+			writeLines(r.Base)
+		}
+		writeMarker("=======", "")
+		writeLines(theirs)
+		writeMarker(">>>>>>>", labels.Theirs)
+		writeLines(suffix)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,127 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import "unicode"
+
+// Like git's --color-moved, blocks with fewer alphanumeric characters than
+// this are not considered moved, so that lone braces and blank lines aren't
+// reported as moves.
+const movedMinAlnum = 20
+
+// A Move links a block of lines that was removed from one place in a diff
+// with where it was added back.
+type Move struct {
+	From   int // Index in the diff of the first DiffMovedFrom part
+	To     int // Index in the diff of the first DiffMovedTo part
+	Length int // Number of lines in the block
+}
+
+// DetectMoves looks for blocks of removed lines that were added back
+// elsewhere, and returns a copy of d where they are marked as DiffMovedFrom
+// and DiffMovedTo, along with the moves that link the two sides. Each block
+// is matched with the longest identical block on the other side.
+func DetectMoves(d []DiffPart) ([]DiffPart, []Move) {
+	result := make([]DiffPart, len(d))
+	copy(result, d)
+
+	// alnum[i] counts the alphanumeric characters of d[:i], and hashes[i]
+	// hashes its lines, so that both can be found for any block
+	values := make([]string, len(d))
+	for i, part := range d {
+		values[i] = part.Value
+	}
+	ids, _ := intern(values, nil)
+	const hashBase = 0x100000001b3
+	alnum := make([]int, len(d)+1)
+	hashes := make([]uint64, len(d)+1)
+	powers := make([]uint64, len(d)+1)
+	powers[0] = 1
+	for i, part := range d {
+		alnum[i+1] = alnum[i]
+		for _, r := range part.Value {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				alnum[i+1]++
+			}
+		}
+		hashes[i+1] = hashes[i]*hashBase + uint64(ids[i]) + 1
+		powers[i+1] = powers[i] * hashBase
+	}
+
+	// Every move starts with the shortest block that has enough alphanumeric
+	// characters, so blocks are only compared with blocks that start the same
+	// way. blockEnd[i] is the end of that block for a block starting at i,
+	// or -1 if the parts with the same action run out first.
+	blockEnd := make([]int, len(d))
+	for start := 0; start < len(d); {
+		runEnd := start
+		for runEnd < len(d) && d[runEnd].Action == d[start].Action {
+			runEnd++
+		}
+		end := start
+		for i := start; i < runEnd; i++ {
+			for end < runEnd && alnum[end]-alnum[i] < movedMinAlnum {
+				end++
+			}
+			blockEnd[i] = -1
+			if alnum[end]-alnum[i] >= movedMinAlnum {
+				blockEnd[i] = end
+			}
+		}
+		start = runEnd
+	}
+	type blockKey struct {
+		hash   uint64
+		length int
+	}
+	key := func(i int) blockKey {
+		length := blockEnd[i] - i
+		return blockKey{hashes[blockEnd[i]] - hashes[i]*powers[length], length}
+	}
+
+	removed := map[blockKey][]int{}
+	for i, part := range d {
+		if part.Action == DiffRemoved && blockEnd[i] != -1 {
+			removed[key(i)] = append(removed[key(i)], i)
+		}
+	}
+
+	moves := []Move{}
+	for j := 0; j < len(result); j++ {
+		if result[j].Action != DiffAdded || blockEnd[j] == -1 {
+			continue
+		}
+
+		// Find the longest run of unmoved removals matching the additions
+		// starting at j
+		best := Move{}
+		for _, i := range removed[key(j)] {
+			length := 0
+			for i+length < len(result) && j+length < len(result) &&
+				result[i+length].Action == DiffRemoved &&
+				result[j+length].Action == DiffAdded &&
+				result[i+length].Value == result[j+length].Value {
+				length++
+			}
+			if length > best.Length {
+				best = Move{From: i, To: j, Length: length}
+			}
+		}
+
+		// Blocks with the same hash might still differ
+		if alnum[best.To+best.Length]-alnum[best.To] < movedMinAlnum {
+			continue
+		}
+
+		for k := 0; k < best.Length; k++ {
+			result[best.From+k].Action = DiffMovedFrom
+			result[best.To+k].Action = DiffMovedTo
+		}
+		moves = append(moves, best)
+		j += best.Length - 1
+	}
+
+	return result, moves
+}
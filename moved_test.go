@@ -0,0 +1,59 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectMoves(t *testing.T) {
+	a := []string{"func moved() {", "return somethingLong", "w", "x", "y", "z", "}"}
+	b := []string{"w", "x", "y", "z", "func moved() {", "return somethingLong", "}", "}"}
+	d, moves := DetectMoves(Diff(a, b))
+
+	expected := []DiffPart{
+		{DiffMovedFrom, "func moved() {"},
+		{DiffMovedFrom, "return somethingLong"},
+		{DiffIdentical, "w"},
+		{DiffIdentical, "x"},
+		{DiffIdentical, "y"},
+		{DiffIdentical, "z"},
+		{DiffMovedTo, "func moved() {"},
+		{DiffMovedTo, "return somethingLong"},
+		{DiffIdentical, "}"},
+		{DiffAdded, "}"},
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("Expected %v, got %v", expected, d)
+	}
+	if expectedMoves := []Move{{From: 0, To: 6, Length: 2}}; !reflect.DeepEqual(moves, expectedMoves) {
+		t.Errorf("Expected moves %v, got %v", expectedMoves, moves)
+	}
+
+	// Short blocks aren't moves
+	d, moves = DetectMoves(Diff([]string{"}", "x"}, []string{"x", "}"}))
+	if len(moves) != 0 || d[0].Action != DiffRemoved {
+		t.Errorf("Expected no moves, got %v", d)
+	}
+}
+
+// Lines that repeat a lot, like closing braces, shouldn't make DetectMoves
+// compare every addition with every removal
+func TestDetectMovesRepeatedLines(t *testing.T) {
+	d := []DiffPart{{DiffRemoved, "return somethingLonger"}}
+	for i := 0; i < 20000; i++ {
+		d = append(d, DiffPart{DiffRemoved, "}"})
+	}
+	for i := 0; i < 20000; i++ {
+		d = append(d, DiffPart{DiffAdded, "}"})
+	}
+	d = append(d, DiffPart{DiffAdded, "return somethingLonger"})
+
+	_, moves := DetectMoves(d)
+	if expected := []Move{{From: 0, To: 40001, Length: 1}}; !reflect.DeepEqual(moves, expected) {
+		t.Errorf("Expected moves %v, got %v", expected, moves)
+	}
+}
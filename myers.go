@@ -0,0 +1,101 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+// myers finds a shortest edit script using the greedy algorithm from
+// Eugene W. Myers' "An O(ND) Difference Algorithm and Its Variations".
+// It runs in O((n+m)·D) time, where D is the size of the edit script, and
+// keeps the O(D²) trace needed to recover the path. If the budget runs out,
+// it hands over to the linear space algorithm, which can give up early.
+func myers(n, m int, equal func(i, j int) bool, b *budget) []DiffAction {
+	maxD := n + m
+	if maxD == 0 {
+		return []DiffAction{}
+	}
+
+	// v[offset+k] is the furthest x reached on diagonal k = x - y
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	// trace[d] is a copy of v[-d..d] after step d, used for the traceback
+	trace := [][]int{}
+
+	for d := 0; d <= maxD; d++ {
+		if b.check() {
+			return myersLinear(n, m, equal, b)
+		}
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Insertion
+			} else {
+				x = v[offset+k-1] + 1 // Removal
+			}
+			y := x - k
+			for x < n && y < m && equal(x, y) {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return myersTraceback(n, m, trace)
+			}
+		}
+
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+	}
+
+	panic("unreachable")
+}
+
+// myersTraceback walks the trace from (n, m) back to (0, 0), repeating the
+// choices made by the forward pass.
+func myersTraceback(n, m int, trace [][]int) []DiffAction {
+	diff := []DiffAction{}
+	x, y := n, m
+	for d := len(trace); d > 0; d-- {
+		prev := trace[d-1] // Indexed by k + d - 1
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+
+		// Follow the snake back to where the edit ended
+		snakeStart := prevX
+		if prevK == k-1 {
+			snakeStart++
+		}
+		for ; x > snakeStart; x-- {
+			diff = append(diff, DiffIdentical)
+			y--
+		}
+
+		if prevK == k+1 {
+			diff = append(diff, DiffAdded)
+		} else {
+			diff = append(diff, DiffRemoved)
+		}
+		x, y = prevX, prevY
+	}
+
+	for ; x > 0; x-- {
+		diff = append(diff, DiffIdentical)
+	}
+
+	for i, j := 0, len(diff)-1; i < j; i, j = i+1, j-1 {
+		diff[i], diff[j] = diff[j], diff[i]
+	}
+	return diff
+}
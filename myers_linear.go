@@ -0,0 +1,223 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+// myersLinear finds a shortest edit script with the linear space refinement
+// from section 4b of Myers' paper. Instead of keeping a trace, it finds the
+// middle snake of an optimal path and recurses on either side of it, so only
+// O(n+m) memory is needed on top of the result.
+//
+// Once the budget runs out, each search for a middle snake stops after a
+// fixed number of changes, and splits at the furthest point reached instead.
+func myersLinear(n, m int, equal func(i, j int) bool, b *budget) []DiffAction {
+	return myersLinearParallel(n, m, equal, b, nil)
+}
+
+// myersLinearParallel is like myersLinear, but the two sides of each middle
+// snake can be diffed at the same time by w.
+func myersLinearParallel(n, m int, equal func(i, j int) bool, b *budget, w *workers) []DiffAction {
+	l := newLinearSpace(n, m, equal, b, w)
+	l.compare(0, n, 0, m)
+	return l.diff
+}
+
+type linearSpace struct {
+	equal   func(i, j int) bool
+	budget  *budget
+	workers *workers
+	offset  int
+	vf, vb  []int // Furthest reaching x for each diagonal, forwards and backwards
+	diff    []DiffAction
+}
+
+// newLinearSpace allocates what is needed to diff n elements against m.
+func newLinearSpace(n, m int, equal func(i, j int) bool, b *budget, w *workers) *linearSpace {
+	maxD := (n+m+1)/2 + 1
+	return &linearSpace{
+		equal:   equal,
+		budget:  b,
+		workers: w,
+		offset:  maxD + 1,
+		vf:      make([]int, 2*maxD+3),
+		vb:      make([]int, 2*maxD+3),
+		diff:    make([]DiffAction, 0, n+m),
+	}
+}
+
+func (l *linearSpace) compare(aLo, aHi, bLo, bHi int) {
+	// Common prefix
+	for aLo < aHi && bLo < bHi && l.equal(aLo, bLo) {
+		l.diff = append(l.diff, DiffIdentical)
+		aLo++
+		bLo++
+	}
+
+	// Common suffix, which is appended once the middle is done
+	suffix := 0
+	for aLo < aHi && bLo < bHi && l.equal(aHi-1, bHi-1) {
+		suffix++
+		aHi--
+		bHi--
+	}
+
+	switch {
+	case aLo == aHi:
+		for ; bLo < bHi; bLo++ {
+			l.diff = append(l.diff, DiffAdded)
+		}
+	case bLo == bHi:
+		for ; aLo < aHi; aLo++ {
+			l.diff = append(l.diff, DiffRemoved)
+		}
+	default:
+		x, y, u, v := l.middleSnake(aLo, aHi, bLo, bHi)
+		if l.workers == nil || x-aLo+y-bLo < parallelMinSize {
+			l.compare(aLo, x, bLo, y)
+			for ; x < u; x++ {
+				l.diff = append(l.diff, DiffIdentical)
+			}
+			l.compare(u, aHi, v, bHi)
+			break
+		}
+
+		// The part before the snake might run on another goroutine, so it
+		// gets its own buffers. The part after it reuses these ones.
+		before := newLinearSpace(x-aLo, y-bLo, l.equal, l.budget, l.workers)
+		wait := l.workers.fork(x-aLo+y-bLo, func() {
+			before.compare(aLo, x, bLo, y)
+		})
+		after := *l
+		after.diff = make([]DiffAction, 0, aHi-u+bHi-v)
+		after.compare(u, aHi, v, bHi)
+		wait()
+
+		l.diff = append(l.diff, before.diff...)
+		for ; x < u; x++ {
+			l.diff = append(l.diff, DiffIdentical)
+		}
+		l.diff = append(l.diff, after.diff...)
+	}
+
+	for ; suffix > 0; suffix-- {
+		l.diff = append(l.diff, DiffIdentical)
+	}
+}
+
+// middleSnake runs the greedy algorithm from both ends at once until the
+// paths overlap, and returns the snake (x, y) to (u, v) in the middle of an
+// optimal path.
+func (l *linearSpace) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int) {
+	n := aHi - aLo
+	m := bHi - bLo
+	delta := n - m
+	odd := delta%2 != 0
+	o := l.offset
+	vf, vb := l.vf, l.vb
+	vf[o+1] = 0
+	vb[o+1] = 0
+
+	for d := 0; d <= (n+m+1)/2; d++ {
+		if l.budget.giveUp(d) {
+			x, y := l.furthest(d-1, n, m)
+			return aLo + x, bLo + y, aLo + x, bLo + y
+		}
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && vf[o+k-1] < vf[o+k+1]) {
+				x = vf[o+k+1]
+			} else {
+				x = vf[o+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && l.equal(aLo+x, bLo+y) {
+				x++
+				y++
+			}
+			vf[o+k] = x
+
+			// Only the backward paths from step d-1 can overlap this one
+			if c := delta - k; odd && c >= -(d-1) && c <= d-1 && x+vb[o+c] >= n {
+				return aLo + startX, bLo + startY, aLo + x, bLo + y
+			}
+		}
+
+		// The backward pass works on the reversed sequences, where diagonal c
+		// corresponds to forward diagonal delta - c.
+		for c := -d; c <= d; c += 2 {
+			var x int
+			if c == -d || (c != d && vb[o+c-1] < vb[o+c+1]) {
+				x = vb[o+c+1]
+			} else {
+				x = vb[o+c-1] + 1
+			}
+			y := x - c
+			startX, startY := x, y
+			for x < n && y < m && l.equal(aHi-1-x, bHi-1-y) {
+				x++
+				y++
+			}
+			vb[o+c] = x
+
+			if k := delta - c; !odd && k >= -d && k <= d && x+vf[o+k] >= n {
+				return aHi - x, bHi - y, aHi - startX, bHi - startY
+			}
+		}
+	}
+
+	panic("unreachable")
+}
+
+// furthest returns the point that made the most progress in either direction
+// after step d of middleSnake, relative to the start of the region. Only the
+// diagonals inside the region are looked at, and the point is never a corner,
+// so that splitting at it always leaves two smaller regions.
+func (l *linearSpace) furthest(d, n, m int) (x, y int) {
+	o := l.offset
+	bestForward, bestBackward := -1, -1
+	var backX, backY int
+	for k := -d; k <= d; k += 2 {
+		if k < -m || k > n {
+			continue
+		}
+		if fx := clampToDiagonal(l.vf[o+k], k, n, m); 2*fx-k > bestForward {
+			bestForward = 2*fx - k
+			x, y = fx, fx-k
+		}
+		if bx := clampToDiagonal(l.vb[o+k], k, n, m); 2*bx-k > bestBackward {
+			bestBackward = 2*bx - k
+			backX, backY = n-bx, m-(bx-k)
+		}
+	}
+	if bestBackward > bestForward {
+		x, y = backX, backY
+	}
+
+	switch x + y {
+	case 0:
+		x = 1
+	case n + m:
+		x = n - 1
+	}
+	return x, y
+}
+
+// clampToDiagonal limits x to the part of diagonal k inside an n by m region.
+func clampToDiagonal(x, k, n, m int) int {
+	if x < k {
+		x = k
+	}
+	if x < 0 {
+		x = 0
+	}
+	if x > m+k {
+		x = m + k
+	}
+	if x > n {
+		x = n
+	}
+	return x
+}
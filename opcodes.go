@@ -0,0 +1,124 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import "fmt"
+
+// OpKind is what an Op does to its range of the first sequence.
+type OpKind int
+
+const (
+	// a[AStart:AEnd] is the same as b[BStart:BEnd]
+	OpEqual OpKind = iota
+	// b[BStart:BEnd] is inserted, AStart == AEnd
+	OpInsert
+	// a[AStart:AEnd] is deleted, BStart == BEnd
+	OpDelete
+	// a[AStart:AEnd] is replaced by b[BStart:BEnd]
+	OpReplace
+)
+
+// The same names as Python's difflib uses
+var opKindNames = []string{
+	OpEqual:   "equal",
+	OpInsert:  "insert",
+	OpDelete:  "delete",
+	OpReplace: "replace",
+}
+
+func (k OpKind) String() string {
+	return opKindNames[k]
+}
+
+// An Op is a range of an edit script, like the opcodes of Python's
+// difflib.SequenceMatcher.get_opcodes. The ops of an edit script cover both
+// sequences in order, and no two ops of the same kind are next to each
+// other.
+type Op struct {
+	Kind         OpKind
+	AStart, AEnd int
+	BStart, BEnd int
+}
+
+func (op Op) String() string {
+	return fmt.Sprintf("{%v a[%v:%v] b[%v:%v]}", op.Kind, op.AStart, op.AEnd, op.BStart, op.BEnd)
+}
+
+// Len returns the number of actions or parts covered by the op.
+func (op Op) Len() int {
+	if op.Kind == OpEqual {
+		return op.AEnd - op.AStart
+	}
+	return op.AEnd - op.AStart + op.BEnd - op.BStart
+}
+
+// OpsFromActions groups an edit script into ops. Each run of changes
+// becomes a single OpInsert, OpDelete or OpReplace.
+func OpsFromActions(d []DiffAction) []Op {
+	ops := []Op{}
+	var i, j int
+	for k := 0; k < len(d); {
+		op := Op{AStart: i, BStart: j}
+		if d[k] == DiffIdentical {
+			for ; k < len(d) && d[k] == DiffIdentical; k++ {
+				i++
+				j++
+			}
+		} else {
+			for ; k < len(d) && d[k] != DiffIdentical; k++ {
+				switch d[k] {
+				case DiffAdded, DiffMovedTo:
+					j++
+				case DiffRemoved, DiffMovedFrom:
+					i++
+				}
+			}
+		}
+		op.AEnd, op.BEnd = i, j
+		switch {
+		case d[k-1] == DiffIdentical:
+			op.Kind = OpEqual
+		case op.AStart == op.AEnd:
+			op.Kind = OpInsert
+		case op.BStart == op.BEnd:
+			op.Kind = OpDelete
+		default:
+			op.Kind = OpReplace
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// OpsFromParts is like OpsFromActions, but takes a diff from Diff or
+// DiffSlices. Moved parts count as removed or added.
+func OpsFromParts[T any](d []Part[T]) []Op {
+	actions := make([]DiffAction, len(d))
+	for i, part := range d {
+		actions[i] = part.Action
+	}
+	return OpsFromActions(actions)
+}
+
+// PartsFromOps turns ops back into a diff of a and b. Within each
+// OpReplace, the removed elements come before the added ones.
+func PartsFromOps[T any](a, b []T, ops []Op) []Part[T] {
+	result := []Part[T]{}
+	for _, op := range ops {
+		if op.Kind == OpEqual {
+			for i := op.AStart; i < op.AEnd; i++ {
+				result = append(result, Part[T]{DiffIdentical, a[i]})
+			}
+			continue
+		}
+		for i := op.AStart; i < op.AEnd; i++ {
+			result = append(result, Part[T]{DiffRemoved, a[i]})
+		}
+		for j := op.BStart; j < op.BEnd; j++ {
+			result = append(result, Part[T]{DiffAdded, b[j]})
+		}
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestOpsFromParts(t *testing.T) {
+	a := []string{"a", "b", "c", "d", "e"}
+	b := []string{"a", "x", "c", "e", "f"}
+	d := Diff(a, b)
+
+	expected := []Op{
+		{OpEqual, 0, 1, 0, 1},
+		{OpReplace, 1, 2, 1, 2},
+		{OpEqual, 2, 3, 2, 3},
+		{OpDelete, 3, 4, 3, 3},
+		{OpEqual, 4, 5, 3, 4},
+		{OpInsert, 5, 5, 4, 5},
+	}
+	ops := OpsFromParts(d)
+	if !reflect.DeepEqual(ops, expected) {
+		t.Errorf("Expected %v, got %v", expected, ops)
+	}
+
+	if result := PartsFromOps(a, b, ops); !reflect.DeepEqual(result, d) {
+		t.Errorf("Expected %v, got %v", d, result)
+	}
+
+	if ops := OpsFromParts([]DiffPart{}); len(ops) != 0 {
+		t.Errorf("Expected no ops, got %v", ops)
+	}
+}
+
+func TestOpsRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(16))
+	for i := 0; i < 200; i++ {
+		a := randomLines(rng, rng.Intn(30), 4)
+		b := randomLines(rng, rng.Intn(30), 4)
+		d := Diff(a, b)
+		ops := OpsFromActions(DiffAlgorithm(len(a), len(b), func(i, j int) bool {
+			return a[i] == b[j]
+		}))
+
+		length := 0
+		for k, op := range ops {
+			if k > 0 && (op.AStart != ops[k-1].AEnd || op.BStart != ops[k-1].BEnd) {
+				t.Fatalf("Ops %v are not contiguous", ops)
+			}
+			length += op.Len()
+		}
+		if length != len(d) {
+			t.Fatalf("Expected ops covering %v parts, got %v", len(d), length)
+		}
+		if result := PartsFromOps(a, b, ops); !reflect.DeepEqual(result, d) {
+			t.Fatalf("Expected %v, got %v", d, result)
+		}
+	}
+}
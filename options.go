@@ -0,0 +1,103 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import "fmt"
+
+// Option changes how Diff and DiffAlgorithm compute the edit script.
+type Option func(*options)
+
+type options struct {
+	algorithm Algorithm
+	weight    func(i int) int
+	budget    *budget // Only set by the Context functions
+
+	// How Diff and LineDiff compare lines, see compare.go
+	normalize       []func(line string) string
+	stripTrailingCR bool
+
+	window  int // Only used by DiffReaders
+	workers int
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Algorithm selects the strategy used to find the edit script.
+type Algorithm int
+
+const (
+	// The greedy Myers algorithm. It takes O((n+m)D) time and keeps O(D²)
+	// memory to recover the edit script.
+	AlgorithmMyers Algorithm = iota
+	// Myers' divide and conquer refinement, which takes about twice as long
+	// but only needs O(n+m) memory. Useful for very large inputs.
+	AlgorithmLinearSpace
+	// Patience diff, which anchors on elements that occur exactly once in
+	// both inputs. It often gives more readable diffs of source code, at the
+	// cost of not always finding a minimal edit script.
+	AlgorithmPatience
+	// Histogram diff, as used by git. It extends patience diff by anchoring
+	// on the elements that occur the fewest times, even if they are not
+	// unique.
+	AlgorithmHistogram
+)
+
+var algorithmNames = []string{
+	AlgorithmMyers:       "myers",
+	AlgorithmLinearSpace: "linear",
+	AlgorithmPatience:    "patience",
+	AlgorithmHistogram:   "histogram",
+}
+
+func (a Algorithm) String() string {
+	return algorithmNames[a]
+}
+
+// ParseAlgorithm returns the algorithm with the given name, as returned by
+// Algorithm.String.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	for a, n := range algorithmNames {
+		if n == name {
+			return Algorithm(a), nil
+		}
+	}
+	return AlgorithmMyers, fmt.Errorf("unknown diff algorithm %q", name)
+}
+
+// Set parses the name of an algorithm, so that an Algorithm can be used as a
+// flag.Value.
+func (a *Algorithm) Set(name string) error {
+	parsed, err := ParseAlgorithm(name)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// WithAlgorithm selects the algorithm used. The default is AlgorithmMyers.
+func WithAlgorithm(a Algorithm) Option {
+	return func(o *options) {
+		o.algorithm = a
+	}
+}
+
+// WithWeight makes the diff maximize the total weight of the matched elements
+// instead of their count, where weight(i) is the weight of the ith element of
+// the first sequence. Elements with a weight of 0 or less are never matched.
+// The default is to weigh every element as 1.
+//
+// Weighted diffs take O(n·m) time, and the algorithm option is ignored.
+func WithWeight(weight func(i int) int) Option {
+	return func(o *options) {
+		o.weight = weight
+	}
+}
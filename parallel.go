@@ -0,0 +1,67 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+// Subproblems with fewer elements than this are not worth another goroutine
+const parallelMinSize = 2048
+
+// WithWorkers lets a diff use up to n goroutines, counting the calling one.
+// AlgorithmLinearSpace diffs the two sides of each middle snake in
+// parallel, and AlgorithmPatience and AlgorithmHistogram diff the regions
+// between their anchors in parallel. The result is always the same as with
+// a single worker. Other algorithms, including the default AlgorithmMyers,
+// ignore this option.
+//
+// With more than one worker, the equal function passed to DiffAlgorithm is
+// called from several goroutines at once.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// workers runs independent parts of a diff on a bounded number of
+// goroutines. A nil *workers runs everything on the calling goroutine.
+type workers struct {
+	slots chan struct{} // Holds a value for each goroutine in use
+}
+
+func newWorkers(n int) *workers {
+	if n <= 1 {
+		return nil
+	}
+	return &workers{slots: make(chan struct{}, n-1)}
+}
+
+// fork runs f, which works on size elements, on another goroutine if one is
+// free and the work is large enough, and otherwise runs it straight away. It
+// returns a function that waits for f to finish. If f panics, for example
+// because the diff was cancelled, the panic is raised again by wait.
+func (w *workers) fork(size int, f func()) (wait func()) {
+	if w == nil || size < parallelMinSize {
+		f()
+		return func() {}
+	}
+	select {
+	case w.slots <- struct{}{}:
+	default:
+		f()
+		return func() {}
+	}
+
+	done := make(chan interface{}, 1)
+	go func() {
+		defer func() {
+			<-w.slots
+			done <- recover()
+		}()
+		f()
+	}()
+	return func() {
+		if r := <-done; r != nil {
+			panic(r)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDiffWorkers(t *testing.T) {
+	rng := rand.New(rand.NewSource(19))
+	for i := 0; i < 5; i++ {
+		// Large enough to split, with some lines unique and some repeated
+		a := randomLines(rng, 20000, 5000)
+		b := make([]string, 0, len(a))
+		for _, line := range a {
+			switch rng.Intn(10) {
+			case 0:
+			case 1:
+				b = append(b, line, randomLines(rng, 1, 5000)[0])
+			default:
+				b = append(b, line)
+			}
+		}
+
+		for _, algorithm := range []Algorithm{AlgorithmLinearSpace, AlgorithmPatience, AlgorithmHistogram} {
+			expected := Diff(a, b, WithAlgorithm(algorithm))
+			result := Diff(a, b, WithAlgorithm(algorithm), WithWorkers(8))
+			if !reflect.DeepEqual(result, expected) {
+				t.Fatalf("%v: Expected the same diff as with one worker", algorithm)
+			}
+		}
+	}
+}
+
+// Cancelling a diff stops all of its goroutines.
+func TestDiffWorkersCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rng := rand.New(rand.NewSource(20))
+	a := randomLines(rng, 10000, 100)
+	b := randomLines(rng, 10000, 100)
+	var calls int64
+	_, _, err := DiffAlgorithmContext(ctx, len(a), len(b), func(i, j int) bool {
+		if atomic.AddInt64(&calls, 1) == 1000000 {
+			cancel()
+		}
+		return a[i] == b[j]
+	}, WithAlgorithm(AlgorithmLinearSpace), WithWorkers(4))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -28,9 +28,9 @@ func ApplyHunks(a []string, hunks []Hunk) ([]string, error) {
 			case diff.DiffIdentical:
 				hunk.aLines++
 				hunk.bLines++
-			case diff.DiffAdded:
+			case diff.DiffAdded, diff.DiffMovedTo:
 				hunk.bLines++
-			case diff.DiffRemoved:
+			case diff.DiffRemoved, diff.DiffMovedFrom:
 				hunk.aLines++
 			}
 		}
@@ -38,7 +38,7 @@ func ApplyHunks(a []string, hunks []Hunk) ([]string, error) {
 		// Find where the hunk matches
 		expectedALines := make([]string, 0, hunk.aLines)
 		for _, part := range hunk.parts {
-			if part.Action == diff.DiffIdentical || part.Action == diff.DiffRemoved {
+			if part.Action != diff.DiffAdded && part.Action != diff.DiffMovedTo {
 				expectedALines = append(expectedALines, part.Value)
 			}
 		}
@@ -93,9 +93,9 @@ func ApplyHunks(a []string, hunks []Hunk) ([]string, error) {
 				case diff.DiffIdentical:
 					aln++
 					b = append(b, part.Value)
-				case diff.DiffAdded:
+				case diff.DiffAdded, diff.DiffMovedTo:
 					b = append(b, part.Value)
-				case diff.DiffRemoved:
+				case diff.DiffRemoved, diff.DiffMovedFrom:
 					aln++
 				}
 			}
@@ -59,3 +59,21 @@ func TestApplyHunksMismatch(t *testing.T) {
 		t.Error("Hunk application was supposed to fail!")
 	}
 }
+
+// Moved lines should apply like removals and additions.
+func TestApplyHunksMoved(t *testing.T) {
+	a := strutils.SplitLines("func moved() {\n\treturn somethingLong\n}\n1\n2\n3\n4\n5\n6\n7\n8\n")
+	b := strutils.SplitLines("1\n2\n3\n4\n5\n6\n7\nfunc moved() {\n\treturn somethingLong\n}\n8\n")
+	d, moves := diff.DetectMoves(diff.Diff(a, b))
+	if len(moves) != 1 {
+		t.Fatalf("Expected 1 move, got %v", moves)
+	}
+
+	reconstructedB, err := ApplyHunks(a, HunkDiff(d))
+	if err != nil {
+		t.Fatalf("Failed to apply hunks: %v", err)
+	}
+	if !reflect.DeepEqual(reconstructedB, b) {
+		t.Errorf("Expected %q, got %q", b, reconstructedB)
+	}
+}
@@ -0,0 +1,324 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package patching
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/wk-y/diff/bindiff"
+	"github.com/wk-y/diff/internal/strutils"
+)
+
+// The line that starts a binary patch in the output of git diff --binary
+const binaryPatchHeader = "GIT binary patch\n"
+
+// The most bytes of compressed data on a line of a binary patch
+const binaryLineBytes = 52
+
+// The digits of git's base 85 encoding
+const base85Digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz!#$%&()*+-;<=>?@^_`{|}~"
+
+// BinaryHunkKind says how a BinaryHunk describes the new contents of a file.
+type BinaryHunkKind int
+
+const (
+	BinaryLiteral BinaryHunkKind = iota // The whole new contents
+	BinaryDelta                         // A git delta from the old contents
+)
+
+var binaryHunkKindNames = []string{
+	BinaryLiteral: "literal",
+	BinaryDelta:   "delta",
+}
+
+func (k BinaryHunkKind) String() string {
+	return binaryHunkKindNames[k]
+}
+
+// A BinaryHunk is a section of a git binary patch.
+type BinaryHunk struct {
+	Kind BinaryHunkKind
+	Data []byte // The new contents or the delta, uncompressed
+}
+
+// A BinaryPatch is a binary patch of a file, as made by git diff --binary.
+type BinaryPatch struct {
+	OldHash, NewHash string      // The BlobHash of the old and new contents, if known
+	Forward          BinaryHunk  // Turns the old contents into the new
+	Reverse          *BinaryHunk // Turns the new contents back into the old, if given
+}
+
+// BinaryDiff returns a binary patch turning a into b, with a reverse section
+// turning b back into a. Like git, each section is a delta if that is
+// smaller once compressed, and the whole contents otherwise.
+func BinaryDiff(a, b []byte) BinaryPatch {
+	reverse := binaryHunk(b, a)
+	return BinaryPatch{
+		OldHash: BlobHash(a),
+		NewHash: BlobHash(b),
+		Forward: binaryHunk(a, b),
+		Reverse: &reverse,
+	}
+}
+
+// BlobHash returns the name git gives a file with the given contents, which
+// is what the index line of a git diff shows.
+func BlobHash(data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %v\x00", len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func binaryHunk(a, b []byte) BinaryHunk {
+	literal := BinaryHunk{BinaryLiteral, b}
+	if len(a) == 0 || len(b) == 0 {
+		return literal
+	}
+	delta := BinaryHunk{BinaryDelta, bindiff.GitDelta(a, b)}
+	if len(compress(delta.Data)) < len(compress(literal.Data)) {
+		return delta
+	}
+	return literal
+}
+
+// String returns the patch as git writes it, starting with a
+// "GIT binary patch" line. The hashes are left to the index line of the
+// header before it.
+func (p BinaryPatch) String() string {
+	var sb strings.Builder
+	sb.WriteString(binaryPatchHeader)
+	sb.WriteString(p.Forward.String())
+	if p.Reverse != nil {
+		sb.WriteString(p.Reverse.String())
+	}
+	return sb.String()
+}
+
+// String returns the hunk as git writes it: its kind and uncompressed size,
+// then the data compressed with zlib and encoded in base 85, then a blank
+// line.
+func (h BinaryHunk) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%v %v\n", h.Kind, len(h.Data))
+	compressed := compress(h.Data)
+	for len(compressed) > 0 {
+		n := len(compressed)
+		if n > binaryLineBytes {
+			n = binaryLineBytes
+		}
+		sb.WriteString(encodeBinaryLine(compressed[:n]))
+		compressed = compressed[n:]
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func compress(data []byte) []byte {
+	var buffer bytes.Buffer
+	w := zlib.NewWriter(&buffer)
+	w.Write(data)
+	w.Close()
+	return buffer.Bytes()
+}
+
+// encodeBinaryLine encodes up to binaryLineBytes bytes as a line of a binary
+// patch. The first character is the number of bytes, A to Z for 1 to 26 and
+// a to z for 27 to 52, followed by groups of 4 bytes in base 85, with the
+// last group padded with zeros.
+func encodeBinaryLine(data []byte) string {
+	var sb strings.Builder
+	if len(data) <= 26 {
+		sb.WriteByte(byte('A' + len(data) - 1))
+	} else {
+		sb.WriteByte(byte('a' + len(data) - 27))
+	}
+	for i := 0; i < len(data); i += 4 {
+		var group uint32
+		for k := 0; k < 4; k++ {
+			group <<= 8
+			if i+k < len(data) {
+				group |= uint32(data[i+k])
+			}
+		}
+		var digits [5]byte
+		for k := len(digits) - 1; k >= 0; k-- {
+			digits[k] = base85Digits[group%85]
+			group /= 85
+		}
+		sb.Write(digits[:])
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// decodeBinaryLine undoes encodeBinaryLine. The line may end with "\n".
+func decodeBinaryLine(line string) ([]byte, error) {
+	line = strings.TrimSuffix(line, "\n")
+	if len(line) == 0 {
+		return nil, errors.New("empty binary patch line")
+	}
+	var n int
+	switch c := line[0]; {
+	case c >= 'A' && c <= 'Z':
+		n = int(c-'A') + 1
+	case c >= 'a' && c <= 'z':
+		n = int(c-'a') + 27
+	default:
+		return nil, fmt.Errorf("invalid binary patch line length %q", c)
+	}
+	if len(line)-1 != (n+3)/4*5 {
+		return nil, fmt.Errorf("binary patch line has %v characters for %v bytes", len(line)-1, n)
+	}
+
+	data := make([]byte, 0, (n+3)/4*4)
+	for i := 1; i < len(line); i += 5 {
+		var group uint64
+		for _, c := range []byte(line[i : i+5]) {
+			digit := strings.IndexByte(base85Digits, c)
+			if digit < 0 {
+				return nil, fmt.Errorf("invalid base 85 digit %q", c)
+			}
+			group = group*85 + uint64(digit)
+		}
+		if group > 0xFFFFFFFF {
+			return nil, errors.New("base 85 group out of range")
+		}
+		data = append(data, byte(group>>24), byte(group>>16), byte(group>>8), byte(group))
+	}
+	return data[:n], nil
+}
+
+// ParseBinaryPatch parses a binary patch made by git diff --binary, from its
+// "GIT binary patch" line to the end of its last section. Anything before
+// the "GIT binary patch" line is skipped, so it can be given a whole git
+// diff of a file, in which case the hashes are taken from its index line.
+func ParseBinaryPatch(patch string) (BinaryPatch, error) {
+	var p BinaryPatch
+	lines := strutils.SplitLines(patch)
+	start := 0
+	for start < len(lines) && lines[start] != binaryPatchHeader {
+		if fields := strings.Fields(lines[start]); len(fields) >= 2 && fields[0] == "index" {
+			if i := strings.Index(fields[1], ".."); i >= 0 {
+				p.OldHash, p.NewHash = fields[1][:i], fields[1][i+2:]
+			}
+		}
+		start++
+	}
+	if start == len(lines) {
+		return BinaryPatch{}, errors.New("no GIT binary patch found")
+	}
+	lines = lines[start+1:]
+
+	var err error
+	p.Forward, lines, err = parseBinaryHunk(lines)
+	if err != nil {
+		return BinaryPatch{}, err
+	}
+	if len(lines) > 0 && (strings.HasPrefix(lines[0], "literal ") || strings.HasPrefix(lines[0], "delta ")) {
+		reverse, _, err := parseBinaryHunk(lines)
+		if err != nil {
+			return BinaryPatch{}, err
+		}
+		p.Reverse = &reverse
+	}
+	return p, nil
+}
+
+// parseBinaryHunk parses a section of a binary patch at the start of lines,
+// and returns the lines after it.
+func parseBinaryHunk(lines []string) (BinaryHunk, []string, error) {
+	var h BinaryHunk
+	if len(lines) == 0 {
+		return h, nil, errors.New("missing binary patch section")
+	}
+	var kind string
+	var size int
+	if n, _ := fmt.Sscanf(lines[0], "%s %d\n", &kind, &size); n != 2 || (kind != "literal" && kind != "delta") {
+		return h, nil, fmt.Errorf("invalid binary patch section header %q", strings.TrimSuffix(lines[0], "\n"))
+	}
+	if kind == "delta" {
+		h.Kind = BinaryDelta
+	}
+
+	var compressed []byte
+	i := 1
+	for ; i < len(lines) && strings.TrimSuffix(lines[i], "\n") != ""; i++ {
+		data, err := decodeBinaryLine(lines[i])
+		if err != nil {
+			return h, nil, err
+		}
+		compressed = append(compressed, data...)
+	}
+	if i < len(lines) {
+		i++ // The blank line ending the section
+	}
+
+	r, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return h, nil, err
+	}
+	// Read one byte more than expected, to notice if there is too much data
+	h.Data, err = io.ReadAll(io.LimitReader(r, int64(size)+1))
+	if err != nil {
+		return h, nil, err
+	}
+	if len(h.Data) != size {
+		return h, nil, fmt.Errorf("binary patch section has %v bytes, expected %v", len(h.Data), size)
+	}
+	return h, lines[i:], nil
+}
+
+// ApplyBinaryPatch applies the forward section of a binary patch to a. If the
+// patch has hashes, like git it checks that a is the file the patch was made
+// for, and that the result is the file it was made from. A hash may be
+// abbreviated.
+func ApplyBinaryPatch(a []byte, p BinaryPatch) ([]byte, error) {
+	if !hashMatches(a, p.OldHash) {
+		return nil, fmt.Errorf("the file has hash %v, but the patch is for %v", BlobHash(a), p.OldHash)
+	}
+	b, err := applyBinaryHunk(a, p.Forward)
+	if err != nil {
+		return nil, err
+	}
+	if !hashMatches(b, p.NewHash) {
+		return nil, fmt.Errorf("the patched file has hash %v, but the patch makes %v", BlobHash(b), p.NewHash)
+	}
+	return b, nil
+}
+
+// hashMatches reports whether data has the given, possibly abbreviated,
+// hash. No hash matches anything, and git's hash of zeros for a missing file
+// matches an empty one.
+func hashMatches(data []byte, hash string) bool {
+	if strings.Trim(hash, "0") == "" {
+		return hash == "" || len(data) == 0
+	}
+	return strings.HasPrefix(BlobHash(data), hash)
+}
+
+func applyBinaryHunk(a []byte, h BinaryHunk) ([]byte, error) {
+	if h.Kind == BinaryLiteral {
+		return append([]byte{}, h.Data...), nil
+	}
+	return bindiff.ApplyGitDelta(a, h.Data)
+}
+
+// isBinaryPatchLine reports whether a line of a diff belongs to a binary
+// patch, other than its "GIT binary patch" line.
+func isBinaryPatchLine(line string) bool {
+	if line == "\n" || strings.HasPrefix(line, "literal ") || strings.HasPrefix(line, "delta ") {
+		return true
+	}
+	_, err := decodeBinaryLine(line)
+	return err == nil
+}
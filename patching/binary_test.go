@@ -0,0 +1,153 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package patching
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// The files of gitBinaryPatch
+func gitBinaryFiles() (a, b []byte) {
+	for i := 0; i < 300; i++ {
+		a = append(a, byte((i*i*31+7)%256))
+	}
+	b = append(append(append([]byte{}, a[:100]...), "\x00changed\x00"...), a[120:]...)
+	return a, b
+}
+
+// Made by git diff --binary
+const gitBinaryPatch = `diff --git a/f.bin b/f.bin
+index 877f0c9e61b109ed8f906182696ae9e55fa58120..de210742fda2b184fc0d3be6e0e216b7e1987d95 100644
+GIT binary patch
+delta 26
+icmZ3(w2)~+iXcOBMq*xiY6` + "`" + `>gZ^?Y$<(kzdtN{RjRSHP}
+
+delta 15
+VcmZ3;w1#Oy%EX%<6Ll<rBmgrm2A==` + "`" + `
+
+`
+
+func TestParseBinaryPatch(t *testing.T) {
+	a, b := gitBinaryFiles()
+	p, err := ParseBinaryPatch(gitBinaryPatch)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p.Forward.Kind != BinaryDelta || p.Reverse == nil || p.Reverse.Kind != BinaryDelta {
+		t.Fatalf("Expected forward and reverse deltas, got %v and %v", p.Forward, p.Reverse)
+	}
+	if p.OldHash != BlobHash(a) || p.NewHash != BlobHash(b) {
+		t.Errorf("Expected hashes %v and %v, got %v and %v", BlobHash(a), BlobHash(b), p.OldHash, p.NewHash)
+	}
+
+	result, err := ApplyBinaryPatch(a, p)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(result, b) {
+		t.Errorf("Expected %v, got %v", b, result)
+	}
+	result, err = applyBinaryHunk(b, *p.Reverse)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(result, a) {
+		t.Errorf("Expected %v, got %v", a, result)
+	}
+
+	// Git's compressed empty file
+	p, err = ParseBinaryPatch("GIT binary patch\nliteral 0\nHcmV?d00001\n\n")
+	if err != nil || p.Forward.Kind != BinaryLiteral || len(p.Forward.Data) != 0 || p.Reverse != nil {
+		t.Errorf("Expected an empty literal, got %v, %v", p, err)
+	}
+}
+
+func TestApplyBinaryPatchHashes(t *testing.T) {
+	a, b := gitBinaryFiles()
+	literal := BinaryHunk{BinaryLiteral, b}
+	testCases := []struct {
+		a                []byte
+		oldHash, newHash string
+		ok               bool
+	}{
+		{a, "", "", true},
+		{b, "", "", true},
+		{a, BlobHash(a), BlobHash(b), true},
+		{a, BlobHash(a)[:7], BlobHash(b)[:7], true},
+		{b, BlobHash(a), BlobHash(b), false},
+		{a, BlobHash(a), BlobHash(a), false},
+		{nil, "0000000000000000000000000000000000000000", BlobHash(b), true},
+		{a, "0000000000000000000000000000000000000000", BlobHash(b), false},
+	}
+	for _, testCase := range testCases {
+		p := BinaryPatch{OldHash: testCase.oldHash, NewHash: testCase.newHash, Forward: literal}
+		result, err := ApplyBinaryPatch(testCase.a, p)
+		if testCase.ok && (err != nil || !bytes.Equal(result, b)) {
+			t.Errorf("Expected %v..%v to apply, got error %v", testCase.oldHash, testCase.newHash, err)
+		}
+		if !testCase.ok && err == nil {
+			t.Errorf("Expected %v..%v not to apply", testCase.oldHash, testCase.newHash)
+		}
+	}
+}
+
+func TestParseBinaryPatchErrors(t *testing.T) {
+	for _, patch := range []string{
+		"literal 0\nHcmV?d00001\n\n",
+		"GIT binary patch\nliteral 1\nHcmV?d00001\n\n",
+		"GIT binary patch\nliteral 0\nIcmV?d00001\n\n",
+		"GIT binary patch\nliteral 0\nHcmV?d0000\"\n\n",
+		"GIT binary patch\ncopy 0\nHcmV?d00001\n\n",
+	} {
+		if _, err := ParseBinaryPatch(patch); err == nil {
+			t.Errorf("Expected an error parsing %q", patch)
+		}
+	}
+}
+
+func TestBinaryDiff(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		a := make([]byte, r.Intn(5000))
+		r.Read(a)
+		b := append([]byte{}, a...)
+		if len(b) > 0 {
+			j := r.Intn(len(b))
+			b = append(b[:j], append([]byte("inserted"), b[j:]...)...)
+		}
+		if i%10 == 0 {
+			a = nil
+		}
+
+		p, err := ParseBinaryPatch(BinaryDiff(a, b).String())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(a) > 100 && p.Forward.Kind != BinaryDelta {
+			t.Errorf("Expected a delta for a small change, got %v", p.Forward.Kind)
+		}
+		result, err := ApplyBinaryPatch(a, p)
+		if err != nil || !bytes.Equal(result, b) {
+			t.Fatalf("Expected the patch to apply, got error %v", err)
+		}
+		result, err = applyBinaryHunk(b, *p.Reverse)
+		if err != nil || !bytes.Equal(result, a) {
+			t.Fatalf("Expected the reverse section to apply, got error %v", err)
+		}
+	}
+}
+
+func TestParseHunksSkipsBinary(t *testing.T) {
+	text := "@@ -1,1 +1,1 @@\n-a\n+b\n"
+	hunks, err := ParseHunks(gitBinaryPatch[len("diff --git a/f.bin b/f.bin\n"):] + text)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "@@ -1 +1 @@\n-a\n+b\n"; len(hunks) != 1 || hunks[0].String() != expected {
+		t.Errorf("Expected only the text hunk, got %v", hunks)
+	}
+}
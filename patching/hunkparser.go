@@ -13,7 +13,8 @@ import (
 	"github.com/wk-y/diff/internal/strutils"
 )
 
-// Parses a diff text back into hunks.
+// Parses a diff text back into hunks. Binary patches made by git diff
+// --binary are skipped, and can be parsed with ParseBinaryPatch.
 func ParseHunks(diffString string) ([]Hunk, error) {
 	hunks := []Hunk{}
 
@@ -24,7 +25,17 @@ func ParseHunks(diffString string) ([]Hunk, error) {
 		lines[len(lines)-1] += "\n"
 	}
 
+	binary := false
 	for _, line := range lines {
+		if line == binaryPatchHeader {
+			binary = true
+			continue
+		}
+		if binary && isBinaryPatchLine(line) {
+			continue
+		}
+		binary = false
+
 		if len(line) == 0 {
 			return hunks, errors.New("blank line encountered in diff")
 		}
@@ -4,7 +4,14 @@
 
 package patching
 
-import "github.com/wk-y/diff"
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/wk-y/diff"
+)
 
 type Hunk struct {
 	aStart, bStart int             // Starting line number
@@ -12,77 +19,189 @@ type Hunk struct {
 	parts          []diff.DiffPart // The lines in the diff
 }
 
-func HunkDiff(d []diff.DiffPart) []Hunk {
-	// Find line numbers
-	aln := make([]int, len(d))
-	bln := make([]int, len(d))
-
-	ai := 0
-	bi := 0
-	for i, part := range d {
-		switch part.Action {
-		case diff.DiffIdentical:
-			ai++
-			bi++
-		case diff.DiffAdded:
-			bi++
-		case diff.DiffRemoved:
-			ai++
+// HunkOption changes how HunkDiff splits a diff into hunks.
+type HunkOption func(*hunkOptions)
+
+type hunkOptions struct {
+	ignoreMatching []*regexp.Regexp
+	ignoreBlank    bool
+	blankOptions   []diff.Option // How lines are compared when judging blankness
+}
+
+// WithIgnoreMatchingLines leaves out changes where every added and removed
+// line matches one of the patterns, like GNU diff's -I. The trailing newline
+// is not part of the text matched. Ignored changes are still shown if they
+// are within the context of another change.
+func WithIgnoreMatchingLines(patterns ...*regexp.Regexp) HunkOption {
+	return func(o *hunkOptions) {
+		o.ignoreMatching = append(o.ignoreMatching, patterns...)
+	}
+}
+
+// WithIgnoreBlankLines leaves out changes where every added and removed line
+// is blank, like GNU diff's -B. Lines are judged by diff.IsBlank with opts,
+// which should be the options the diff was made with, so that for example
+// lines of white space are blank with diff.WithIgnoreAllSpace. Together with
+// WithIgnoreMatchingLines, changes where each line is either blank or matches
+// a pattern are left out.
+func WithIgnoreBlankLines(opts ...diff.Option) HunkOption {
+	return func(o *hunkOptions) {
+		o.ignoreBlank = true
+		o.blankOptions = opts
+	}
+}
+
+// ignored reports whether every line of a run of changes is blank or matches
+// one of the ignored patterns.
+func (o hunkOptions) ignored(changes []diff.DiffPart) bool {
+	if len(o.ignoreMatching) == 0 && !o.ignoreBlank {
+		return false
+	}
+	for _, part := range changes {
+		if o.ignoreBlank && diff.IsBlank(part.Value, o.blankOptions...) {
+			continue
+		}
+		line := strings.TrimSuffix(part.Value, "\n")
+		matched := false
+		for _, pattern := range o.ignoreMatching {
+			if pattern.MatchString(line) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
 		}
+	}
+	return true
+}
 
-		// Forcing it to be at least 1 improves compatibility with gnu diff,
-		// maybe there is more nuance to how it should be done, however.
+// HunkDiff splits a diff into hunks of changes with up to three lines of
+// context. Changes are first slid to their most readable position with
+// diff.Compact.
+func HunkDiff(d []diff.DiffPart, opts ...HunkOption) []Hunk {
+	var o hunkOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	d = diff.Compact(d)
+	ops := diff.OpsFromParts(d)
+
+	// Index of the first part of each op
+	partStarts := make([]int, len(ops)+1)
+	for i, op := range ops {
+		partStarts[i+1] = partStarts[i] + op.Len()
+	}
+
+	// Find the changes that start or extend a hunk
+	changed := make([]bool, len(ops))
+	for i, op := range ops {
+		changed[i] = op.Kind != diff.OpEqual && !o.ignored(d[partStarts[i]:partStarts[i+1]])
+	}
 
-		if ai > 1 {
-			aln[i] = ai
+	// lineNumbers returns the number of lines of a and b up to and including
+	// part p.
+	lineNumbers := func(p int) (a, b int) {
+		i := sort.Search(len(ops), func(i int) bool {
+			return partStarts[i+1] > p
+		})
+		op := ops[i]
+		if op.Kind == diff.OpEqual {
+			a = op.AStart + p - partStarts[i] + 1
+			b = op.BStart + p - partStarts[i] + 1
 		} else {
-			aln[i] = 1
+			a, b = op.AStart, op.BStart
+			for _, part := range d[partStarts[i] : p+1] {
+				switch part.Action {
+				case diff.DiffAdded, diff.DiffMovedTo:
+					b++
+				case diff.DiffRemoved, diff.DiffMovedFrom:
+					a++
+				}
+			}
 		}
 
-		if bi > 1 {
-			bln[i] = bi
-		} else {
-			bln[i] = 1
+		// Forcing it to be at least 1 improves compatibility with gnu diff,
+		// maybe there is more nuance to how it should be done, however.
+		if a < 1 {
+			a = 1
+		}
+		if b < 1 {
+			b = 1
 		}
+		return a, b
 	}
 
 	const contextLines = 3
 
 	hunks := make([]Hunk, 0)
-	for i := 0; i < len(d); i++ {
-		if d[i].Action != diff.DiffIdentical {
-			dStart := i - contextLines
-			if dStart < 0 {
-				dStart = 0
-			}
-			newHunk := Hunk{
-				aStart: aln[dStart],
-				bStart: bln[dStart],
-			}
+	for i := 0; i < len(ops); i++ {
+		if !changed[i] {
+			continue
+		}
 
-			distancePastEdit := 0
-			j := i
-			for j < len(d)-1 && distancePastEdit <= contextLines*2 {
-				j++
-				distancePastEdit++
-				if d[j].Action != diff.DiffIdentical {
-					distancePastEdit = 0
-				}
+		// Changes with up to twice the context between them share a hunk
+		last := i
+		for j, gap := i+1, 0; j < len(ops) && gap <= contextLines*2; j++ {
+			if changed[j] {
+				last = j
+				gap = 0
+			} else {
+				gap += ops[j].Len()
 			}
-			i = j
+		}
 
-			dEnd := j
-			if distancePastEdit > contextLines {
-				dEnd -= distancePastEdit - contextLines
-			}
+		dStart := partStarts[i] - contextLines
+		if dStart < 0 {
+			dStart = 0
+		}
+		dEnd := partStarts[last+1] - 1 + contextLines
+		if dEnd > len(d)-1 {
+			dEnd = len(d) - 1
+		}
 
-			newHunk.aLines = aln[dEnd] + 1 - newHunk.aStart
-			newHunk.bLines = bln[dEnd] + 1 - newHunk.bStart
-			newHunk.parts = d[dStart : dEnd+1]
+		newHunk := Hunk{parts: d[dStart : dEnd+1]}
+		newHunk.aStart, newHunk.bStart = lineNumbers(dStart)
+		aEnd, bEnd := lineNumbers(dEnd)
+		newHunk.aLines = aEnd + 1 - newHunk.aStart
+		newHunk.bLines = bEnd + 1 - newHunk.bStart
 
-			hunks = append(hunks, newHunk)
-		}
+		hunks = append(hunks, newHunk)
+		i = last
 	}
 
 	return hunks
 }
+
+// Reverse returns a hunk that undoes h, with the added and removed lines
+// and the line numbers of the two files swapped.
+func (h Hunk) Reverse() Hunk {
+	return Hunk{
+		aStart: h.bStart,
+		bStart: h.aStart,
+		aLines: h.bLines,
+		bLines: h.aLines,
+		parts:  diff.Invert(h.parts),
+	}
+}
+
+// ReverseHunks reverses each hunk, so that applying the result undoes the
+// hunks.
+func ReverseHunks(hunks []Hunk) []Hunk {
+	reversed := make([]Hunk, len(hunks))
+	for i, hunk := range hunks {
+		reversed[i] = hunk.Reverse()
+	}
+	return reversed
+}
+
+// Reversed returns a binary patch that undoes p, using its reverse section.
+// It fails if p has no reverse section.
+func (p BinaryPatch) Reversed() (BinaryPatch, error) {
+	if p.Reverse == nil {
+		return BinaryPatch{}, errors.New("binary patch has no reverse section")
+	}
+	forward := p.Forward
+	return BinaryPatch{OldHash: p.NewHash, NewHash: p.OldHash, Forward: *p.Reverse, Reverse: &forward}, nil
+}
@@ -0,0 +1,129 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package patching
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/wk-y/diff"
+	"github.com/wk-y/diff/internal/strutils"
+)
+
+func TestHunkDiffIgnoreBlankLines(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		opts     []diff.Option
+		expected int
+	}{
+		{"a\nb\n", "a\n\nb\n", nil, 0},
+		{"a\n \t\nb\n", "a\nb\n", nil, 1},
+		{"a\n \t\nb\n", "a\nb\n", []diff.Option{diff.WithIgnoreAllSpace()}, 0},
+		{"a\nb\n", "a\n\r\nb\n", nil, 1},
+		{"a\nb\n", "a\n\r\nb\n", []diff.Option{diff.WithStripTrailingCR()}, 0},
+		{"a\nb\n", "a\nc\n", nil, 1},
+		{"a\nb\n", "a\n\nc\n", nil, 1},
+		// Only the blank change is left out
+		{"\nx\n1\n2\n3\n4\n5\n6\n7\n8\ny\n", "x\n1\n2\n3\n4\n5\n6\n7\n8\nz\n", nil, 1},
+	}
+
+	for _, testCase := range testCases {
+		d := diff.LineDiff(testCase.a, testCase.b, testCase.opts...)
+		hunks := HunkDiff(d, WithIgnoreBlankLines(testCase.opts...))
+		if len(hunks) != testCase.expected {
+			t.Errorf("LineDiff(%q, %q): Expected %v hunks, got %v", testCase.a, testCase.b, testCase.expected, len(hunks))
+		}
+	}
+
+	// Blank lines and lines matching -I patterns are left out together
+	d := diff.LineDiff("a\nb\n", "a\n\n// Generated\nb\n")
+	hunks := HunkDiff(d, WithIgnoreBlankLines(), WithIgnoreMatchingLines(regexp.MustCompile("^// Generated")))
+	if len(hunks) != 0 {
+		t.Errorf("Expected no hunks, got %v", hunks)
+	}
+}
+
+func TestHunkDiffIgnoreMatchingLines(t *testing.T) {
+	a := "// Generated at 10:00\nx\n1\n2\n3\n4\n5\n6\n7\n8\ny\n"
+	b := "// Generated at 11:00\nx\n1\n2\n3\n4\n5\n6\n7\n8\nz\n"
+	d := diff.LineDiff(a, b)
+
+	hunks := HunkDiff(d, WithIgnoreMatchingLines(regexp.MustCompile("^// Generated")))
+	if len(hunks) != 1 || hunks[0].aStart != 8 {
+		t.Errorf("Expected only the last hunk, got %v", hunks)
+	}
+
+	// Changes have to match one of the patterns to be ignored
+	hunks = HunkDiff(d, WithIgnoreMatchingLines(regexp.MustCompile("^x$"), regexp.MustCompile("10:00")))
+	if len(hunks) != 2 {
+		t.Errorf("Expected 2 hunks, got %v", hunks)
+	}
+	hunks = HunkDiff(d, WithIgnoreMatchingLines(regexp.MustCompile("10:00"), regexp.MustCompile("11:00")))
+	if len(hunks) != 1 {
+		t.Errorf("Expected 1 hunk, got %v", hunks)
+	}
+
+	// Ignored changes near other changes are still shown
+	d = diff.LineDiff("// Generated at 10:00\nx\n", "// Generated at 11:00\ny\n")
+	expected := "@@ -1,2 +1,2 @@\n-// Generated at 10:00\n-x\n+// Generated at 11:00\n+y\n"
+	if result := DiffString(d, WithIgnoreMatchingLines(regexp.MustCompile("Generated"))); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestReverseHunks(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	hunks := HunkDiff(diff.LineDiff(a, b))
+
+	reversed := ReverseHunks(hunks)
+	expected := "@@ -1,5 +1,5 @@\n a\n-B\n+b\n c\n d\n e\n@@ -8,4 +8,3 @@\n h\n i\n j\n-k\n"
+	result := ""
+	for _, hunk := range reversed {
+		result += hunk.String()
+	}
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	undone, err := ApplyHunks(strutils.SplitLines(b), reversed)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result := strings.Join(undone, ""); result != a {
+		t.Errorf("Expected %q, got %q", a, result)
+	}
+}
+
+func TestBinaryPatchReversed(t *testing.T) {
+	a, b := gitBinaryFiles()
+	p, err := ParseBinaryPatch(gitBinaryPatch)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	reversed, err := p.Reversed()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result, err := ApplyBinaryPatch(b, reversed)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(result, a) {
+		t.Errorf("Expected %v, got %v", a, result)
+	}
+	if twice, err := reversed.Reversed(); err != nil || !reflect.DeepEqual(twice, p) {
+		t.Errorf("Expected %v, got %v, %v", p, twice, err)
+	}
+
+	p.Reverse = nil
+	if _, err := p.Reversed(); err == nil {
+		t.Errorf("Expected an error for a patch without a reverse section")
+	}
+}
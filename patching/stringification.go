@@ -11,6 +11,16 @@ import (
 	"github.com/wk-y/diff"
 )
 
+// Terminal colors, the same as git's defaults
+const (
+	colorRemoved   = "\x1b[31m"
+	colorAdded     = "\x1b[32m"
+	colorMovedFrom = "\x1b[1;35m"
+	colorMovedTo   = "\x1b[1;36m"
+	colorHeader    = "\x1b[36m"
+	colorReset     = "\x1b[m"
+)
+
 // Information of what part of a file is covered by a hunk
 type hunkCoverage struct {
 	start, count int
@@ -25,8 +35,8 @@ func (c hunkCoverage) String() string {
 
 // DiffString formats an array of DiffParts into a unified diff.
 // DiffString will produce strange results if d is not from LineDiff.
-func DiffString(d []diff.DiffPart) string {
-	hunks := HunkDiff(d)
+func DiffString(d []diff.DiffPart, opts ...HunkOption) string {
+	hunks := HunkDiff(d, opts...)
 	hunkStrings := make([]string, len(hunks))
 	for i, hunk := range hunks {
 		hunkStrings[i] = hunk.String()
@@ -40,9 +50,9 @@ func (h Hunk) String() string {
 	diffLines = append(diffLines, header)
 	for _, part := range h.parts {
 		switch part.Action {
-		case diff.DiffAdded:
+		case diff.DiffAdded, diff.DiffMovedTo:
 			diffLines = append(diffLines, fmt.Sprint("+", part.Value))
-		case diff.DiffRemoved:
+		case diff.DiffRemoved, diff.DiffMovedFrom:
 			diffLines = append(diffLines, fmt.Sprint("-", part.Value))
 		case diff.DiffIdentical:
 			diffLines = append(diffLines, fmt.Sprint(" ", part.Value))
@@ -56,3 +66,41 @@ func (h Hunk) String() string {
 	}
 	return strings.Join(diffLines, "")
 }
+
+// ColorDiffString is like DiffString, but colors the output for a terminal.
+// Moved lines from diff.DetectMoves get their own colors.
+func ColorDiffString(d []diff.DiffPart, opts ...HunkOption) string {
+	hunks := HunkDiff(d, opts...)
+	hunkStrings := make([]string, len(hunks))
+	for i, hunk := range hunks {
+		hunkStrings[i] = hunk.ColorString()
+	}
+	return strings.Join(hunkStrings, "")
+}
+
+// ColorString formats the hunk like String, but with terminal colors.
+func (h Hunk) ColorString() string {
+	diffLines := make([]string, 0)
+	header := fmt.Sprintf("@@ -%v +%v @@", hunkCoverage{h.aStart, h.aLines}, hunkCoverage{h.bStart, h.bLines})
+	diffLines = append(diffLines, colorHeader, header, colorReset, "\n")
+	for _, part := range h.parts {
+		line := strings.TrimSuffix(part.Value, "\n")
+		switch part.Action {
+		case diff.DiffAdded:
+			diffLines = append(diffLines, colorAdded, "+", line, colorReset, "\n")
+		case diff.DiffRemoved:
+			diffLines = append(diffLines, colorRemoved, "-", line, colorReset, "\n")
+		case diff.DiffMovedTo:
+			diffLines = append(diffLines, colorMovedTo, "+", line, colorReset, "\n")
+		case diff.DiffMovedFrom:
+			diffLines = append(diffLines, colorMovedFrom, "-", line, colorReset, "\n")
+		case diff.DiffIdentical:
+			diffLines = append(diffLines, " ", line, "\n")
+		}
+
+		if !strings.HasSuffix(part.Value, "\n") {
+			diffLines = append(diffLines, "\\ No newline at end of file\n")
+		}
+	}
+	return strings.Join(diffLines, "")
+}
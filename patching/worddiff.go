@@ -0,0 +1,118 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package patching
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wk-y/diff"
+	"github.com/wk-y/diff/internal/strutils"
+)
+
+// WordDiffFormat is how changed words are shown, following git diff --word-diff.
+type WordDiffFormat int
+
+const (
+	// Changes are wrapped in [-removed-] and {+added+}
+	WordDiffPlain WordDiffFormat = iota
+	// Changes are shown in red and green, without markers
+	WordDiffColor
+	// Each run of words is on its own line, prefixed with ' ', '-', or '+',
+	// and newlines in the input are shown as lines containing only '~'
+	WordDiffPorcelain
+)
+
+var wordDiffFormatNames = []string{
+	WordDiffPlain:     "plain",
+	WordDiffColor:     "color",
+	WordDiffPorcelain: "porcelain",
+}
+
+func (f WordDiffFormat) String() string {
+	return wordDiffFormatNames[f]
+}
+
+// Set parses the name of a format, so that a WordDiffFormat can be used as a
+// flag.Value.
+func (f *WordDiffFormat) Set(name string) error {
+	for format, n := range wordDiffFormatNames {
+		if n == name {
+			*f = WordDiffFormat(format)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown word diff format %q", name)
+}
+
+// WordDiffString formats an array of DiffParts like DiffString, except that
+// changed lines are compared word by word.
+func WordDiffString(d []diff.DiffPart, format WordDiffFormat, opts ...HunkOption) string {
+	hunks := HunkDiff(d, opts...)
+	hunkStrings := make([]string, len(hunks))
+	for i, hunk := range hunks {
+		hunkStrings[i] = hunk.WordDiffString(format)
+	}
+	return strings.Join(hunkStrings, "")
+}
+
+// WordDiffString formats the hunk, comparing changed lines word by word.
+func (h Hunk) WordDiffString(format WordDiffFormat) string {
+	var sb strings.Builder
+	header := fmt.Sprintf("@@ -%v +%v @@", hunkCoverage{h.aStart, h.aLines}, hunkCoverage{h.bStart, h.bLines})
+	if format == WordDiffColor {
+		header = colorHeader + header + colorReset
+	}
+	sb.WriteString(header + "\n")
+
+	for _, part := range diff.Refine(h.parts, diff.SplitWords) {
+		for _, line := range strutils.SplitLines(part.Value) {
+			text := strings.TrimSuffix(line, "\n")
+			if text != "" {
+				sb.WriteString(formatWords(part.Action, text, format))
+			}
+			if len(text) != len(line) {
+				if format == WordDiffPorcelain {
+					sb.WriteString("~")
+				}
+				sb.WriteString("\n")
+			}
+		}
+	}
+
+	if s := sb.String(); !strings.HasSuffix(s, "\n") {
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func formatWords(action diff.DiffAction, text string, format WordDiffFormat) string {
+	switch format {
+	case WordDiffColor:
+		switch action {
+		case diff.DiffRemoved:
+			return colorRemoved + text + colorReset
+		case diff.DiffAdded:
+			return colorAdded + text + colorReset
+		}
+	case WordDiffPorcelain:
+		switch action {
+		case diff.DiffRemoved:
+			return "-" + text + "\n"
+		case diff.DiffAdded:
+			return "+" + text + "\n"
+		default:
+			return " " + text + "\n"
+		}
+	default:
+		switch action {
+		case diff.DiffRemoved:
+			return "[-" + text + "-]"
+		case diff.DiffAdded:
+			return "{+" + text + "+}"
+		}
+	}
+	return text
+}
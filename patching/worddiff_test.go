@@ -0,0 +1,28 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package patching
+
+import (
+	"testing"
+
+	"github.com/wk-y/diff"
+)
+
+func TestWordDiffString(t *testing.T) {
+	d := diff.LineDiff("a\nthe quick brown fox\nb\n", "a\nthe quick red fox\nnew line\nb\n")
+
+	testCases := []struct {
+		format   WordDiffFormat
+		expected string
+	}{
+		{WordDiffPlain, "@@ -1,3 +1,4 @@\na\nthe quick [-brown-]{+red+} fox\n{+new line+}\nb\n"},
+		{WordDiffPorcelain, "@@ -1,3 +1,4 @@\n a\n~\n the quick \n-brown\n+red\n  fox\n~\n+new line\n~\n b\n~\n"},
+	}
+	for _, testCase := range testCases {
+		if result := WordDiffString(d, testCase.format); result != testCase.expected {
+			t.Errorf("%v: Expected %q, got %q", testCase.format, testCase.expected, result)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import "sort"
+
+// patience implements Bram Cohen's patience diff. Elements that occur exactly
+// once in both a and b are used as anchors, the longest sequence of anchors
+// that appear in the same order in both is matched, and the gaps between
+// them are diffed recursively. Gaps without unique elements fall back to
+// Myers' algorithm. The gaps can be diffed in parallel by w.
+func patience(a, b []int, budget *budget, w *workers) []DiffAction {
+	p := patienceDiff{a: a, b: b, budget: budget, workers: w, diff: make([]DiffAction, 0, len(a)+len(b))}
+	p.compare(0, len(a), 0, len(b))
+	return p.diff
+}
+
+type patienceDiff struct {
+	a, b    []int // Element ids, equal elements share an id
+	budget  *budget
+	workers *workers
+	diff    []DiffAction
+}
+
+func (p *patienceDiff) compare(aLo, aHi, bLo, bHi int) {
+	p.budget.check()
+
+	// Common prefix
+	for aLo < aHi && bLo < bHi && p.a[aLo] == p.b[bLo] {
+		p.diff = append(p.diff, DiffIdentical)
+		aLo++
+		bLo++
+	}
+
+	// Common suffix, which is appended once the middle is done
+	suffix := 0
+	for aLo < aHi && bLo < bHi && p.a[aHi-1] == p.b[bHi-1] {
+		suffix++
+		aHi--
+		bHi--
+	}
+
+	anchors := uniqueCommonSubsequence(p.a[aLo:aHi], p.b[bLo:bHi])
+	switch {
+	case len(anchors) == 0:
+		p.diff = append(p.diff, myers(aHi-aLo, bHi-bLo, func(i, j int) bool {
+			return p.a[aLo+i] == p.b[bLo+j]
+		}, p.budget)...)
+	case p.workers != nil:
+		// Diff each gap into its own slice, some of them on other goroutines
+		gaps := make([]patienceDiff, len(anchors)+1)
+		waits := make([]func(), len(gaps))
+		i, j := aLo, bLo
+		for k := range gaps {
+			aEnd, bEnd := aHi, bHi
+			if k < len(anchors) {
+				aEnd, bEnd = aLo+anchors[k].i, bLo+anchors[k].j
+			}
+			gap := &gaps[k]
+			*gap = patienceDiff{a: p.a, b: p.b, budget: p.budget, workers: p.workers}
+			aStart, bStart := i, j
+			waits[k] = p.workers.fork(aEnd-aStart+bEnd-bStart, func() {
+				gap.compare(aStart, aEnd, bStart, bEnd)
+			})
+			i, j = aEnd+1, bEnd+1
+		}
+		for k, wait := range waits {
+			wait()
+			p.diff = append(p.diff, gaps[k].diff...)
+			if k < len(anchors) {
+				p.diff = append(p.diff, DiffIdentical)
+			}
+		}
+	default:
+		i, j := aLo, bLo
+		for _, anchor := range anchors {
+			p.compare(i, aLo+anchor.i, j, bLo+anchor.j)
+			p.diff = append(p.diff, DiffIdentical)
+			i = aLo + anchor.i + 1
+			j = bLo + anchor.j + 1
+		}
+		p.compare(i, aHi, j, bHi)
+	}
+
+	for ; suffix > 0; suffix-- {
+		p.diff = append(p.diff, DiffIdentical)
+	}
+}
+
+// A pair of matched indexes
+type match struct {
+	i, j int
+}
+
+// uniqueCommonSubsequence finds the elements that occur exactly once in both
+// a and b, and returns the longest sequence of them that is in the same order
+// in both.
+func uniqueCommonSubsequence(a, b []int) []match {
+	type occurrence struct {
+		aCount, bCount int
+		i, j           int
+	}
+	occurrences := map[int]*occurrence{}
+	for i, id := range a {
+		o := occurrences[id]
+		if o == nil {
+			o = &occurrence{}
+			occurrences[id] = o
+		}
+		o.aCount++
+		o.i = i
+	}
+	for j, id := range b {
+		if o := occurrences[id]; o != nil {
+			o.bCount++
+			o.j = j
+		}
+	}
+
+	unique := []match{}
+	for _, id := range a {
+		if o := occurrences[id]; o.aCount == 1 && o.bCount == 1 {
+			unique = append(unique, match{o.i, o.j})
+		}
+	}
+	return longestIncreasingMatches(unique)
+}
+
+// longestIncreasingMatches takes matches ordered by i, and returns the longest
+// subsequence of them that is also ordered by j. This is the patience sorting
+// step that gives the algorithm its name.
+func longestIncreasingMatches(matches []match) []match {
+	if len(matches) == 0 {
+		return nil
+	}
+
+	// tops[k] is the index of the match on top of pile k, and each match
+	// points back to the top of the previous pile when it was placed.
+	tops := []int{}
+	back := make([]int, len(matches))
+	for k, m := range matches {
+		pile := sort.Search(len(tops), func(p int) bool {
+			return matches[tops[p]].j > m.j
+		})
+		if pile > 0 {
+			back[k] = tops[pile-1]
+		} else {
+			back[k] = -1
+		}
+		if pile == len(tops) {
+			tops = append(tops, k)
+		} else {
+			tops[pile] = k
+		}
+	}
+
+	result := make([]match, len(tops))
+	for k, p := len(tops)-1, tops[len(tops)-1]; k >= 0; k, p = k-1, back[p] {
+		result[k] = matches[p]
+	}
+	return result
+}
@@ -0,0 +1,156 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+// A diff algorithm that works on a pair of sequences through an equality
+// callback
+type algorithmFunc func(n, m int, equal func(i, j int) bool) []DiffAction
+
+// trimmed runs algorithm on everything except the common prefix and suffix
+// of the two sequences, which are always identical.
+func trimmed(n, m int, equal func(i, j int) bool, algorithm algorithmFunc) []DiffAction {
+	prefix, suffix := commonAffixes(n, m, equal)
+	return withAffixes(prefix, suffix, algorithm(n-prefix-suffix, m-prefix-suffix, func(i, j int) bool {
+		return equal(prefix+i, prefix+j)
+	}))
+}
+
+// trimmedPrefix is like trimmed, but only strips the common prefix. Myers'
+// algorithm can match the start of the common suffix with earlier elements,
+// so stripping it can change the result.
+func trimmedPrefix(n, m int, equal func(i, j int) bool, algorithm algorithmFunc) []DiffAction {
+	prefix, _ := commonAffixes(n, m, equal)
+	return withAffixes(prefix, 0, algorithm(n-prefix, m-prefix, func(i, j int) bool {
+		return equal(prefix+i, prefix+j)
+	}))
+}
+
+// prepared runs Myers' algorithm on interned sequences after stripping the
+// common prefix and suffix, and discarding elements that can't be matched.
+// The result is the same as running it on the whole sequences.
+func prepared(a, b []int, algorithm algorithmFunc) []DiffAction {
+	prefix, suffix := commonAffixes(len(a), len(b), func(i, j int) bool {
+		return a[i] == b[j]
+	})
+
+	// Leave in the part of the suffix that could be matched with elements
+	// before it. The suffix ends up starting with an element that doesn't
+	// occur in the middle of either sequence, which every optimal path has
+	// to match with its counterpart.
+	inMiddle := make([]bool, len(a)+len(b)) // Ids are smaller than the number of elements
+	for _, id := range a[prefix : len(a)-suffix] {
+		inMiddle[id] = true
+	}
+	for _, id := range b[prefix : len(b)-suffix] {
+		inMiddle[id] = true
+	}
+	for suffix > 0 && inMiddle[a[len(a)-suffix]] {
+		suffix--
+	}
+
+	return withAffixes(prefix, suffix, discarded(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], algorithm))
+}
+
+// commonAffixes returns the lengths of the common prefix and suffix of two
+// sequences, without letting them overlap.
+func commonAffixes(n, m int, equal func(i, j int) bool) (prefix, suffix int) {
+	for prefix < n && prefix < m && equal(prefix, prefix) {
+		prefix++
+	}
+	for suffix < n-prefix && suffix < m-prefix && equal(n-1-suffix, m-1-suffix) {
+		suffix++
+	}
+	return
+}
+
+// withAffixes adds the common prefix and suffix back around d.
+func withAffixes(prefix, suffix int, d []DiffAction) []DiffAction {
+	result := make([]DiffAction, prefix, prefix+len(d)+suffix) // The zero value is DiffIdentical
+	result = append(result, d...)
+	for ; suffix > 0; suffix-- {
+		result = append(result, DiffIdentical)
+	}
+	return result
+}
+
+// discarded runs algorithm on interned sequences, leaving out the elements
+// that don't occur in the other sequence at all, like GNU diff does. Those
+// elements can never be matched, so they are just put back in as removals
+// and additions afterwards.
+func discarded(a, b []int, algorithm algorithmFunc) []DiffAction {
+	ids := 0
+	for _, id := range a {
+		if id >= ids {
+			ids = id + 1
+		}
+	}
+	for _, id := range b {
+		if id >= ids {
+			ids = id + 1
+		}
+	}
+	inA := make([]bool, ids)
+	for _, id := range a {
+		inA[id] = true
+	}
+	inB := make([]bool, ids)
+	for _, id := range b {
+		inB[id] = true
+	}
+
+	// Indexes of the elements that are kept
+	keptA := make([]int, 0, len(a))
+	for i, id := range a {
+		if inB[id] {
+			keptA = append(keptA, i)
+		}
+	}
+	keptB := make([]int, 0, len(b))
+	for j, id := range b {
+		if inA[id] {
+			keptB = append(keptB, j)
+		}
+	}
+
+	d := algorithm(len(keptA), len(keptB), func(i, j int) bool {
+		return a[keptA[i]] == b[keptB[j]]
+	})
+	if len(keptA) == len(a) && len(keptB) == len(b) {
+		return d
+	}
+
+	// Put the discarded elements back before the next kept one
+	result := make([]DiffAction, 0, len(a)+len(b))
+	var i, j, k, l int // Positions in a, b, keptA, and keptB
+	restore := func(aEnd, bEnd int) {
+		for ; i < aEnd; i++ {
+			result = append(result, DiffRemoved)
+		}
+		for ; j < bEnd; j++ {
+			result = append(result, DiffAdded)
+		}
+	}
+	for _, action := range d {
+		switch action {
+		case DiffIdentical:
+			restore(keptA[k], keptB[l])
+			i++
+			j++
+			k++
+			l++
+		case DiffRemoved:
+			restore(keptA[k], j)
+			i++
+			k++
+		case DiffAdded:
+			restore(i, keptB[l])
+			j++
+			l++
+		}
+		result = append(result, action)
+	}
+	restore(len(a), len(b))
+	return result
+}
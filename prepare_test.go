@@ -0,0 +1,54 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// Trimming and discarding lines before diffing must not change the result.
+func TestDiffPrepared(t *testing.T) {
+	testCases := []struct {
+		algorithm Algorithm
+		core      func(n, m int, equal func(i, j int) bool, b *budget) []DiffAction
+	}{
+		{AlgorithmMyers, myers},
+		{AlgorithmLinearSpace, myersLinear},
+	}
+
+	rng := rand.New(rand.NewSource(3))
+	for iter := 0; iter < 2000; iter++ {
+		a := randomLines(rng, rng.Intn(40), 2+rng.Intn(20))
+		b := randomLines(rng, rng.Intn(40), 2+rng.Intn(20))
+		// Give the inputs a common prefix and suffix
+		if rng.Intn(2) == 0 {
+			b = append(append([]string{}, a[:rng.Intn(len(a)+1)]...), b...)
+		}
+		if rng.Intn(2) == 0 {
+			a = append(a, b[rng.Intn(len(b)+1):]...)
+		}
+
+		for _, testCase := range testCases {
+			expected := removalsFirst(testCase.core(len(a), len(b), func(i, j int) bool {
+				return a[i] == b[j]
+			}, nil))
+			result := DiffAlgorithm(len(a), len(b), func(i, j int) bool {
+				return a[i] == b[j]
+			}, WithAlgorithm(testCase.algorithm))
+			if !reflect.DeepEqual(result, expected) {
+				t.Fatalf("%v: DiffAlgorithm of %v and %v gave %v, expected %v", testCase.algorithm, a, b, result, expected)
+			}
+
+			parts := Diff(a, b, WithAlgorithm(testCase.algorithm))
+			for k := range parts {
+				if len(parts) != len(expected) || parts[k].Action != expected[k] {
+					t.Fatalf("%v: Diff of %v and %v gave %v, expected %v", testCase.algorithm, a, b, parts, expected)
+				}
+			}
+		}
+	}
+}
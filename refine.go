@@ -0,0 +1,93 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Refine takes a line diff from Diff or LineDiff, and diffs each run of
+// removed lines against the added lines next to it at a finer granularity.
+// split breaks the text of a run into the tokens to compare, such as
+// SplitWords or SplitRunes. Moved lines are treated as plain removals and
+// additions. Adjacent parts with the same action are joined,
+// so a part of the result may cover part of a line or several lines.
+func Refine(d []DiffPart, split func(string) []string, opts ...Option) []DiffPart {
+	result := []DiffPart{}
+	for i := 0; i < len(d); {
+		if d[i].Action == DiffIdentical {
+			result = appendMerged(result, d[i])
+			i++
+			continue
+		}
+
+		var removed, added strings.Builder
+		for ; i < len(d) && d[i].Action != DiffIdentical; i++ {
+			if d[i].Action == DiffRemoved || d[i].Action == DiffMovedFrom {
+				removed.WriteString(d[i].Value)
+			} else {
+				added.WriteString(d[i].Value)
+			}
+		}
+
+		if removed.Len() == 0 || added.Len() == 0 {
+			result = appendMerged(result, DiffPart{DiffRemoved, removed.String()})
+			result = appendMerged(result, DiffPart{DiffAdded, added.String()})
+			continue
+		}
+		for _, part := range Diff(split(removed.String()), split(added.String()), opts...) {
+			result = appendMerged(result, part)
+		}
+	}
+	return result
+}
+
+// appendMerged appends part to d, joining it with the last part if they have
+// the same action.
+func appendMerged(d []DiffPart, part DiffPart) []DiffPart {
+	if part.Value == "" {
+		return d
+	}
+	if len(d) > 0 && d[len(d)-1].Action == part.Action {
+		d[len(d)-1].Value += part.Value
+		return d
+	}
+	return append(d, part)
+}
+
+// SplitWords splits s into words, runs of whitespace, and newlines, so that
+// joining the result gives back s.
+func SplitWords(s string) []string {
+	words := []string{}
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if r != '\n' {
+			space := unicode.IsSpace(r)
+			for size < len(s) {
+				next, nextSize := utf8.DecodeRuneInString(s[size:])
+				if next == '\n' || unicode.IsSpace(next) != space {
+					break
+				}
+				size += nextSize
+			}
+		}
+		words = append(words, s[:size])
+		s = s[size:]
+	}
+	return words
+}
+
+// SplitRunes splits s into its runes.
+func SplitRunes(s string) []string {
+	runes := make([]string, 0, len(s))
+	for len(s) > 0 {
+		_, size := utf8.DecodeRuneInString(s)
+		runes = append(runes, s[:size])
+		s = s[size:]
+	}
+	return runes
+}
@@ -0,0 +1,45 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRefine(t *testing.T) {
+	d := LineDiff("same\nthe quick brown fox\ngone\n", "same\nthe quick red fox\n")
+
+	expected := []DiffPart{
+		{DiffIdentical, "same\nthe quick "},
+		{DiffRemoved, "brown"},
+		{DiffAdded, "red"},
+		{DiffIdentical, " fox\n"},
+		{DiffRemoved, "gone\n"},
+	}
+	if result := Refine(d, SplitWords); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	expected = []DiffPart{
+		{DiffIdentical, "same\nthe quick "},
+		{DiffRemoved, "b"},
+		{DiffIdentical, "r"},
+		{DiffRemoved, "own"},
+		{DiffAdded, "ed"},
+		{DiffIdentical, " fox\n"},
+		{DiffRemoved, "gone\n"},
+	}
+	if result := Refine(d, SplitRunes); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	expected := []string{"func", " ", "f()", "\t ", "{", "\n", "\n", "  ", "é"}
+	if result := SplitWords("func f()\t {\n\n  é"); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, result)
+	}
+}
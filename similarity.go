@@ -0,0 +1,132 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+// Similarity counts how much of two sequences a diff of them matched.
+type Similarity struct {
+	Matches    int // Elements in both sequences
+	Deletions  int // Elements only in the first sequence
+	Insertions int // Elements only in the second sequence
+}
+
+// Compare diffs a and b and returns how similar they are. Ratio gives the
+// similarity ratio and Distance the number of insertions and deletions,
+// while Levenshtein also counts substitutions. With the default
+// AlgorithmMyers, Matches is the length of the longest common subsequence.
+func Compare[T comparable](a, b []T, opts ...Option) Similarity {
+	o := newOptions(opts)
+	return SimilarityFromActions(o.diff(len(a), len(b), func(i, j int) bool {
+		return a[i] == b[j]
+	}, func() ([]int, []int) {
+		return intern(a, b)
+	}))
+}
+
+// SimilarityFromActions counts the matches and changes of an edit script.
+// For a minimal edit script, Matches is the length of the longest common
+// subsequence.
+func SimilarityFromActions(d []DiffAction) Similarity {
+	var s Similarity
+	for _, action := range d {
+		switch action {
+		case DiffIdentical:
+			s.Matches++
+		case DiffRemoved, DiffMovedFrom:
+			s.Deletions++
+		case DiffAdded, DiffMovedTo:
+			s.Insertions++
+		}
+	}
+	return s
+}
+
+// SimilarityFromParts is like SimilarityFromActions, but takes a diff from
+// Diff or DiffSlices.
+func SimilarityFromParts[T any](d []Part[T]) Similarity {
+	actions := make([]DiffAction, len(d))
+	for i, part := range d {
+		actions[i] = part.Action
+	}
+	return SimilarityFromActions(actions)
+}
+
+// Ratio returns 2·M/T, where M is the number of matches and T is the total
+// length of both sequences, like Python's difflib.SequenceMatcher.ratio. It
+// is 1 if the sequences are the same and 0 if they have nothing in common.
+// Two empty sequences have a ratio of 1.
+func (s Similarity) Ratio() float64 {
+	total := 2*s.Matches + s.Deletions + s.Insertions
+	if total == 0 {
+		return 1
+	}
+	return float64(2*s.Matches) / float64(total)
+}
+
+// Distance returns the number of insertions and deletions needed to turn
+// the first sequence into the second.
+func (s Similarity) Distance() int {
+	return s.Deletions + s.Insertions
+}
+
+// QuickRatio returns an upper bound on the Ratio of a diff of a and b,
+// without diffing them, like Python's difflib.SequenceMatcher.quick_ratio.
+// It counts every element of b that has an unused equal element in a as a
+// match, regardless of order. It takes O(n+m) time.
+func QuickRatio[T comparable](a, b []T) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+	available := map[T]int{}
+	for _, x := range a {
+		available[x]++
+	}
+	matches := 0
+	for _, x := range b {
+		if available[x] > 0 {
+			available[x]--
+			matches++
+		}
+	}
+	return float64(2*matches) / float64(len(a)+len(b))
+}
+
+// Levenshtein returns the smallest number of insertions, deletions and
+// substitutions needed to turn a into b. It takes O(n·m) time and O(m)
+// memory.
+func Levenshtein[T comparable](a, b []T) int {
+	// Common ends don't change the distance
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	// row[j] is the distance between the first i elements of a and the
+	// first j elements of b
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			substitution := diagonal
+			if a[i-1] != b[j-1] {
+				substitution++
+			}
+			diagonal = row[j]
+			row[j] = substitution
+			if row[j] > diagonal+1 {
+				row[j] = diagonal + 1 // Deletion
+			}
+			if row[j] > row[j-1]+1 {
+				row[j] = row[j-1] + 1 // Insertion
+			}
+		}
+	}
+	return row[len(b)]
+}
@@ -0,0 +1,83 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	// The example from difflib's documentation
+	a := []rune("abcd")
+	b := []rune("bcde")
+	s := SimilarityFromParts(DiffSlices(a, b))
+	expected := Similarity{Matches: 3, Deletions: 1, Insertions: 1}
+	if s != expected {
+		t.Errorf("Expected %v, got %v", expected, s)
+	}
+	if s := Compare(a, b); s != expected {
+		t.Errorf("Expected %v, got %v", expected, s)
+	}
+	if s := Compare(a, b, WithAlgorithm(AlgorithmHistogram)); s != expected {
+		t.Errorf("Expected %v, got %v", expected, s)
+	}
+	if ratio := s.Ratio(); ratio != 0.75 {
+		t.Errorf("Expected a ratio of 0.75, got %v", ratio)
+	}
+	if distance := s.Distance(); distance != 2 {
+		t.Errorf("Expected a distance of 2, got %v", distance)
+	}
+
+	if ratio := (Similarity{}).Ratio(); ratio != 1 {
+		t.Errorf("Expected empty sequences to have a ratio of 1, got %v", ratio)
+	}
+}
+
+func TestQuickRatio(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected float64
+	}{
+		{"abcd", "bcde", 0.75},
+		{"abcd", "dcba", 1},
+		{"aab", "abb", 2.0 / 3},
+		{"", "", 1},
+		{"ab", "", 0},
+	}
+	for _, testCase := range testCases {
+		if result := QuickRatio([]rune(testCase.a), []rune(testCase.b)); result != testCase.expected {
+			t.Errorf("QuickRatio(%q, %q): Expected %v, got %v", testCase.a, testCase.b, testCase.expected, result)
+		}
+	}
+
+	// It is an upper bound on the ratio
+	rng := rand.New(rand.NewSource(17))
+	for i := 0; i < 200; i++ {
+		a := randomLines(rng, rng.Intn(20), 4)
+		b := randomLines(rng, rng.Intn(20), 4)
+		if ratio := SimilarityFromParts(Diff(a, b)).Ratio(); QuickRatio(a, b) < ratio {
+			t.Fatalf("QuickRatio(%q, %q) = %v is below the ratio %v", a, b, QuickRatio(a, b), ratio)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"", "abc", 3},
+		{"abc", "abc", 0},
+		{"abcdef", "azced", 3},
+	}
+	for _, testCase := range testCases {
+		if result := Levenshtein([]rune(testCase.a), []rune(testCase.b)); result != testCase.expected {
+			t.Errorf("Levenshtein(%q, %q): Expected %v, got %v", testCase.a, testCase.b, testCase.expected, result)
+		}
+	}
+}
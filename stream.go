@@ -0,0 +1,159 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import (
+	"bufio"
+	"io"
+)
+
+// The window used by DiffReaders if WithWindow isn't given
+const defaultWindow = 10000
+
+// DiffReaders streams after a run of this many identical lines
+const streamSyncLines = 8
+
+// WithWindow sets how many lines of each input DiffReaders keeps in memory
+// at most. The default is 10000.
+func WithWindow(lines int) Option {
+	return func(o *options) {
+		o.window = lines
+	}
+}
+
+// DiffReaders diffs the lines of a and b like LineDiff, but reads them
+// incrementally and calls yield with each part as soon as it is known,
+// stopping early if yield returns an error. It only keeps a window of lines
+// of each input in memory, so it suits very large inputs where the changes
+// are local.
+//
+// Identical lines are passed through as they are read. At the first
+// difference, up to a window of lines of each input is read and diffed,
+// and the changes up to the last run of identical lines in the windows are
+// reported. Then the inputs are in sync again. If the windows have nothing in
+// common, all of their lines are reported as changed, so changes spread
+// further apart than the window may not be minimal.
+func DiffReaders(a, b io.Reader, yield func(DiffPart) error, opts ...Option) error {
+	o := newOptions(opts)
+	window := o.window
+	if window <= 0 {
+		window = defaultWindow
+	}
+
+	s := stream{
+		readers: [2]*bufio.Reader{bufio.NewReader(a), bufio.NewReader(b)},
+		yield:   yield,
+	}
+	for {
+		// Pass identical lines through
+		for {
+			if err := s.fill(1); err != nil {
+				return err
+			}
+			if len(s.lines[0]) == 0 || len(s.lines[1]) == 0 {
+				break
+			}
+			compared := o.compared([]string{s.lines[0][0], s.lines[1][0]})
+			if compared[0] != compared[1] {
+				break
+			}
+			if err := s.emit([]DiffAction{DiffIdentical}); err != nil {
+				return err
+			}
+		}
+
+		if err := s.fill(window); err != nil {
+			return err
+		}
+		if len(s.lines[0]) == 0 && len(s.lines[1]) == 0 {
+			return nil
+		}
+
+		d := o.diffLines(s.lines[0], s.lines[1])
+		if s.eof[0] && s.eof[1] {
+			return s.emit(d)
+		}
+
+		// Report the changes up to the last long enough run of identical
+		// lines, or everything if there is none. The lines after it are
+		// diffed again with the next window, as the end of this one may have
+		// cut them off from their matches.
+		end := len(d)
+		for j := len(d); j > 0; {
+			if d[j-1] != DiffIdentical {
+				j--
+				continue
+			}
+			i := j
+			for i > 0 && d[i-1] == DiffIdentical {
+				i--
+			}
+			if j-i >= streamSyncLines {
+				end = i
+				break
+			}
+			j = i
+		}
+		if err := s.emit(d[:end]); err != nil {
+			return err
+		}
+	}
+}
+
+// stream holds the lines of the two inputs of DiffReaders that have been
+// read but not reported yet.
+type stream struct {
+	readers [2]*bufio.Reader
+	lines   [2][]string
+	eof     [2]bool
+	yield   func(DiffPart) error
+}
+
+// fill reads lines until there are n of each input, or the input ends.
+func (s *stream) fill(n int) error {
+	for k, r := range s.readers {
+		for !s.eof[k] && len(s.lines[k]) < n {
+			line, err := r.ReadString('\n')
+			if line != "" {
+				s.lines[k] = append(s.lines[k], line)
+			}
+			if err == io.EOF {
+				s.eof[k] = true
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// emit reports the parts of an edit script of the first lines of each
+// input, and drops those lines.
+func (s *stream) emit(d []DiffAction) error {
+	var i, j int
+	for _, action := range d {
+		var part DiffPart
+		switch action {
+		case DiffIdentical:
+			part = DiffPart{action, s.lines[0][i]}
+			i++
+			j++
+		case DiffAdded:
+			part = DiffPart{action, s.lines[1][j]}
+			j++
+		case DiffRemoved:
+			part = DiffPart{action, s.lines[0][i]}
+			i++
+		}
+		if err := s.yield(part); err != nil {
+			return err
+		}
+	}
+
+	// The dropped lines are freed once fill needs to grow the slices
+	s.lines[0] = s.lines[0][i:]
+	s.lines[1] = s.lines[1][j:]
+	return nil
+}
@@ -0,0 +1,123 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func diffReaders(a, b string, opts ...Option) ([]DiffPart, error) {
+	result := []DiffPart{}
+	err := DiffReaders(strings.NewReader(a), strings.NewReader(b), func(part DiffPart) error {
+		result = append(result, part)
+		return nil
+	}, opts...)
+	return result, err
+}
+
+func TestDiffReaders(t *testing.T) {
+	rng := rand.New(rand.NewSource(18))
+	for i := 0; i < 200; i++ {
+		a := strings.Join(randomLines(rng, rng.Intn(50), 3), "\n")
+		b := strings.Join(randomLines(rng, rng.Intn(50), 3), "\n")
+
+		// With a window larger than the inputs it is the same as LineDiff
+		expected := LineDiff(a, b)
+		result, err := diffReaders(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Fatalf("Expected %v, got %v", expected, result)
+		}
+
+		result, err = diffReaders(a, b, WithWindow(5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resultA, resultB := extractOriginals(result)
+		if strings.Join(resultA, "") != a || strings.Join(resultB, "") != b {
+			t.Fatalf("%v is not a diff of %q and %q", result, a, b)
+		}
+	}
+}
+
+// Far apart changes are found minimally with a small window.
+func TestDiffReadersLocalChanges(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&a, "line %v\n", i)
+		switch i % 1000 {
+		case 0:
+			fmt.Fprintf(&b, "changed %v\n", i)
+		case 500:
+			fmt.Fprintf(&b, "line %v\ninserted %v\n", i, i)
+		default:
+			fmt.Fprintf(&b, "line %v\n", i)
+		}
+	}
+
+	result, err := diffReaders(a.String(), b.String(), WithWindow(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := LineDiff(a.String(), b.String())
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected the same diff as LineDiff")
+	}
+}
+
+// Dense changes are reported a window at a time, and the windows still
+// line up with each other.
+func TestDiffReadersDenseChanges(t *testing.T) {
+	rng := rand.New(rand.NewSource(18))
+	var a, b strings.Builder
+	for i := 0; i < 20000; i++ {
+		line := fmt.Sprintf("%v\n", rng.Intn(5))
+		a.WriteString(line)
+		if i%12 == 0 {
+			line = "changed\n"
+		}
+		b.WriteString(line)
+	}
+
+	result, err := diffReaders(a.String(), b.String(), WithWindow(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultA, resultB := extractOriginals(result)
+	if strings.Join(resultA, "") != a.String() || strings.Join(resultB, "") != b.String() {
+		t.Fatalf("The result is not a diff of the inputs")
+	}
+	changes := 0
+	for _, part := range result {
+		if part.Action != DiffIdentical {
+			changes++
+		}
+	}
+	if expected := 2 * 1667; changes != expected {
+		t.Errorf("Expected %v changed lines, got %v", expected, changes)
+	}
+}
+
+func TestDiffReadersStop(t *testing.T) {
+	stop := errors.New("stop")
+	parts := 0
+	err := DiffReaders(strings.NewReader("a\nb\nc\n"), strings.NewReader("a\nc\n"), func(part DiffPart) error {
+		parts++
+		if part.Action != DiffIdentical {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || parts != 2 {
+		t.Errorf("Expected to stop after 2 parts, stopped after %v with %v", parts, err)
+	}
+}
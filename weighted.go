@@ -0,0 +1,155 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package diff
+
+// WeightedDiff is like Diff, but maximizes the total weight of the identical
+// lines instead of their count. For example, weighting lines by their length
+// prefers matching one long line over several blank lines.
+func WeightedDiff(a, b []string, weight func(string) int) []DiffPart {
+	return Diff(a, b, WithWeight(func(i int) int {
+		return weight(a[i])
+	}))
+}
+
+// weighted finds the edit script whose matched elements have the largest
+// total weight. Myers' algorithm relies on every match being worth the same,
+// so this uses Hirschberg's algorithm instead, which takes O(n·m) time but
+// only O(n+m) memory. If the budget runs out, the rest of the diff ignores
+// the weights and uses the linear space Myers algorithm.
+func weighted(n, m int, equal func(i, j int) bool, weight func(i int) int, b *budget) []DiffAction {
+	w := weightedDiff{
+		equal:    equal,
+		weight:   weight,
+		budget:   b,
+		forward:  make([]int, m+1),
+		backward: make([]int, m+1),
+		diff:     make([]DiffAction, 0, n+m),
+	}
+	w.compare(0, n, 0, m)
+	return w.diff
+}
+
+type weightedDiff struct {
+	equal             func(i, j int) bool
+	weight            func(i int) int
+	budget            *budget
+	forward, backward []int // Scores of the best matchings against each prefix or suffix of b
+	diff              []DiffAction
+}
+
+func (w *weightedDiff) compare(aLo, aHi, bLo, bHi int) {
+	if w.budget.check() {
+		w.budget.useHeuristic()
+		w.diff = append(w.diff, myersLinear(aHi-aLo, bHi-bLo, func(i, j int) bool {
+			return w.equal(aLo+i, bLo+j)
+		}, w.budget)...)
+		return
+	}
+
+	switch {
+	case aLo == aHi:
+		for ; bLo < bHi; bLo++ {
+			w.diff = append(w.diff, DiffAdded)
+		}
+	case bLo == bHi:
+		for ; aLo < aHi; aLo++ {
+			w.diff = append(w.diff, DiffRemoved)
+		}
+	case aHi-aLo == 1:
+		// Match the single element with the first equal element of b, if
+		// matching it is worth anything
+		j := bLo
+		if w.weight(aLo) > 0 {
+			for j < bHi && !w.equal(aLo, j) {
+				j++
+			}
+		} else {
+			j = bHi
+		}
+		if j == bHi {
+			w.diff = append(w.diff, DiffRemoved)
+			j = bLo
+		} else {
+			for k := bLo; k < j; k++ {
+				w.diff = append(w.diff, DiffAdded)
+			}
+			w.diff = append(w.diff, DiffIdentical)
+			j++
+		}
+		for ; j < bHi; j++ {
+			w.diff = append(w.diff, DiffAdded)
+		}
+	default:
+		// Split a in half, and find where to split b so that the best
+		// matchings of both halves add up to the most
+		mid := (aLo + aHi) / 2
+		forward := w.scoreForward(aLo, mid, bLo, bHi)
+		backward := w.scoreBackward(mid, aHi, bLo, bHi)
+		if w.budget.check() {
+			// The scores are incomplete
+			w.compare(aLo, aHi, bLo, bHi)
+			return
+		}
+		best, split := -1, bLo
+		for j := range forward {
+			if score := forward[j] + backward[j]; score > best {
+				best = score
+				split = bLo + j
+			}
+		}
+		w.compare(aLo, mid, bLo, split)
+		w.compare(mid, aHi, split, bHi)
+	}
+}
+
+// scoreForward returns the weight of the best matching of a[aLo:aHi] against
+// each prefix b[bLo:bLo+j].
+func (w *weightedDiff) scoreForward(aLo, aHi, bLo, bHi int) []int {
+	row := w.forward[:bHi-bLo+1]
+	for j := range row {
+		row[j] = 0
+	}
+	for i := aLo; i < aHi && !w.budget.check(); i++ {
+		weight := w.weight(i)
+		diagonal := 0
+		for j := 1; j < len(row); j++ {
+			best := row[j]
+			if row[j-1] > best {
+				best = row[j-1]
+			}
+			if weight > 0 && diagonal+weight > best && w.equal(i, bLo+j-1) {
+				best = diagonal + weight
+			}
+			diagonal = row[j]
+			row[j] = best
+		}
+	}
+	return row
+}
+
+// scoreBackward returns the weight of the best matching of a[aLo:aHi] against
+// each suffix b[bLo+j:bHi].
+func (w *weightedDiff) scoreBackward(aLo, aHi, bLo, bHi int) []int {
+	row := w.backward[:bHi-bLo+1]
+	for j := range row {
+		row[j] = 0
+	}
+	for i := aHi - 1; i >= aLo && !w.budget.check(); i-- {
+		weight := w.weight(i)
+		diagonal := 0
+		for j := len(row) - 2; j >= 0; j-- {
+			best := row[j]
+			if row[j+1] > best {
+				best = row[j+1]
+			}
+			if weight > 0 && diagonal+weight > best && w.equal(i, bLo+j) {
+				best = diagonal + weight
+			}
+			diagonal = row[j]
+			row[j] = best
+		}
+	}
+	return row
+}